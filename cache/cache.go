@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// Cache holds loaded values per key until they expire or the cache gets invalidated
+type Cache[K comparable, V any] struct {
+	mutex sync.Mutex
+
+	ttl     time.Duration
+	entries map[K]entry[V]
+
+	// incremented on invalidation, values loaded before are not stored
+	generation uint64
+}
+
+type entry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// New creates a cache and registers it for invalidation notifications on the topic
+func New[K comparable, V any](topic string, ttl time.Duration) *Cache[K, V] {
+
+	var (
+		cache = &Cache[K, V]{
+			ttl:     ttl,
+			entries: make(map[K]entry[V]),
+		}
+	)
+
+	register(topic, cache)
+
+	return cache
+}
+
+// Get returns the cached value for the key or calls load to get it.
+// The loaded value is only cached if load returns true.
+func (cache *Cache[K, V]) Get(key K, load func(key K) (V, bool)) (value V) {
+
+	var (
+		ok         bool
+		generation uint64
+
+		cached entry[V]
+	)
+
+	cache.mutex.Lock()
+
+	cached, ok = cache.entries[key]
+	if ok && time.Now().Before(cached.expiresAt) {
+		cache.mutex.Unlock()
+
+		return cached.value
+	}
+
+	generation = cache.generation
+
+	cache.mutex.Unlock()
+
+	// load without holding the lock, concurrent loads of the same key are fine
+	value, ok = load(key)
+	if !ok {
+		return
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	// cache was invalidated while loading, the value might be stale already
+	if generation != cache.generation {
+		return
+	}
+
+	cache.entries[key] = entry[V]{
+		value:     value,
+		expiresAt: time.Now().Add(cache.ttl),
+	}
+
+	return
+}
+
+// Invalidate drops all cached values
+func (cache *Cache[K, V]) Invalidate() {
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.generation++
+	cache.entries = make(map[K]entry[V])
+}
@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// NotifyChannel is the Postgres channel the database triggers notify on
+// with the topic of the changed data as payload
+const NotifyChannel = "loginapi_cache"
+
+const listenRetryDelay = 5 * time.Second
+
+type invalidator interface {
+	Invalidate()
+}
+
+var (
+	topicsMutex sync.Mutex
+	topics      = make(map[string][]invalidator)
+)
+
+func register(topic string, cache invalidator) {
+
+	topicsMutex.Lock()
+	defer topicsMutex.Unlock()
+
+	topics[topic] = append(topics[topic], cache)
+}
+
+// InvalidateTopic drops all values of the caches registered for the topic,
+// an empty topic invalidates all caches
+func InvalidateTopic(topic string) {
+
+	topicsMutex.Lock()
+	defer topicsMutex.Unlock()
+
+	for cacheTopic, caches := range topics {
+
+		if topic != "" && topic != cacheTopic {
+			continue
+		}
+
+		for _, cache := range caches {
+			cache.Invalidate()
+		}
+	}
+}
+
+// Listen invalidates caches on notifications until the context is done
+func Listen(ctx context.Context, pool *pgxpool.Pool) {
+
+	for ctx.Err() == nil {
+
+		err := listen(ctx, pool)
+		if ctx.Err() != nil {
+			return
+		}
+
+		fmt.Printf("Cache notification listener failed: %s\n", err.Error())
+
+		// notifications might have been missed
+		InvalidateTopic("")
+
+		time.Sleep(listenRetryDelay)
+	}
+}
+
+func listen(ctx context.Context, pool *pgxpool.Pool) (err error) {
+
+	var (
+		pooledConn *pgxpool.Conn
+		conn       *pgx.Conn
+
+		notification *pgconn.Notification
+	)
+
+	pooledConn, err = pool.Acquire(ctx)
+	if err != nil {
+		return
+	}
+
+	// the listening connection must not go back to the pool still subscribed
+	conn = pooledConn.Hijack()
+	defer conn.Close(context.Background())
+
+	_, err = conn.Exec(ctx, "LISTEN "+NotifyChannel)
+	if err != nil {
+		return
+	}
+
+	for {
+
+		notification, err = conn.WaitForNotification(ctx)
+		if err != nil {
+			return
+		}
+
+		InvalidateTopic(notification.Payload)
+	}
+}
@@ -0,0 +1,155 @@
+package endpoints
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"PSF-LoginAPI/password"
+	"PSF-LoginAPI/response"
+	"PSF-LoginAPI/utils"
+)
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" binding:"required"`
+	NewPassword     string `json:"newPassword" binding:"required"`
+}
+
+// ChangePassword replaces the password of the token account and revokes all other sessions and the game token,
+// the response contains a new token for the current session
+func ChangePassword(gc *gin.Context) {
+
+	var (
+		err error
+
+		ok bool
+
+		statusCode int
+
+		token string
+		hash  string
+
+		accountID  int64
+		generation int64
+
+		account *Account
+
+		changeRequest ChangePasswordRequest
+
+		claims = gc.MustGet("claims").(jwt.MapClaims)
+	)
+
+	err = gc.BindJSON(&changeRequest)
+	if err != nil {
+		fmt.Println("Could not parse request body as POST ChangePassword")
+
+		return
+	}
+
+	accountID, ok = getClaimsAccount(gc)
+	if !ok {
+		gc.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	statusCode, account = loadAccount(`"id" = $1`, accountID)
+	if statusCode == response.ResponseErrorSuccess {
+		statusCode = checkAccountPassword(gc.Request.Context(), account, changeRequest.CurrentPassword)
+	}
+	if statusCode != response.ResponseErrorSuccess {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(statusCode),
+		)
+
+		return
+	}
+
+	if !checkPasswordPolicy(gc, changeRequest.NewPassword, account.Username) {
+		return
+	}
+
+	hash, err = utils.GetPasswordPool().Hash(gc.Request.Context(), utils.GetPasswordHasher(), changeRequest.NewPassword)
+	if err != nil {
+
+		statusCode = response.ResponseErrorInternalServerBusy
+		if !errors.Is(err, password.ErrOverloaded) {
+			statusCode = response.ResponseErrorInternalPasswordHashFailed
+
+			fmt.Printf("Error hashing new password of account %d: %s\n", account.ID, err.Error())
+		}
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(statusCode),
+		)
+
+		return
+	}
+
+	statusCode, generation = setAccountPassword(account.ID, hash)
+	if statusCode != response.ResponseErrorSuccess {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(statusCode),
+		)
+
+		return
+	}
+
+	fmt.Printf("User [%s] with ID %d changed password\n", account.Username, account.ID)
+
+	writeAuditEvent(gc, account.ID, AuditEventPasswordChanged, nil)
+
+	// keep the current session
+	claims = getCarriedClaims(claims)
+	claims["session"] = generation
+
+	token, err = utils.GenerateToken(&claims)
+	if err != nil {
+
+		fmt.Printf("Token singing failed: %s\n", err.Error())
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(response.ResponseErrorInternalTokenCreationFailed),
+		)
+
+		return
+	}
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		response.TokenResponse{
+			DefaultResponse: response.DefaultResponse{
+				Status: response.ResponseErrorSuccess,
+			},
+			Token: token,
+		},
+	)
+}
+
+// writes the policy response if the password breaks the password policy,
+// returns false if the request has to stop
+func checkPasswordPolicy(gc *gin.Context, newPassword string, username string) bool {
+
+	var (
+		err = utils.GetPasswordPolicy().Check(newPassword, username)
+	)
+
+	if err == nil {
+		return true
+	}
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		response.CreateErrorResponseWithText(response.ResponseErrorPasswordPolicy, err.Error()),
+	)
+
+	return false
+}
@@ -0,0 +1,32 @@
+package endpoints
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// returns the ID path parameter, aborts the request if it is not a number
+func getIDParam(gc *gin.Context, name string) (id int64, ok bool) {
+
+	var (
+		err error
+	)
+
+	id, err = strconv.ParseInt(gc.Param(name), 10, 64)
+	if err != nil {
+		gc.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	return id, true
+}
+
+// returns the account of the admin, 0 for requests with the admin API key
+func getAdminAccount(gc *gin.Context) int64 {
+
+	account, _ := getClaimsAccount(gc)
+
+	return account
+}
@@ -0,0 +1,211 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"PSF-LoginAPI/response"
+	"PSF-LoginAPI/utils"
+)
+
+type AdminLauncherChannelRequest struct {
+	Channel string `json:"channel" binding:"required"`
+}
+
+type launcherChannelMembers struct {
+	LauncherChannel
+	Accounts []int64 `db:"accounts"`
+}
+
+func AdminGetLauncherChannels(gc *gin.Context) {
+
+	var (
+		err error
+
+		rows pgx.Rows
+
+		channels []launcherChannelMembers
+
+		channelsResponse = response.LauncherChannelsResponse{
+			DefaultResponse: response.DefaultResponse{
+				Status: response.ResponseErrorSuccess,
+			},
+			Channels: []response.LauncherChannel{},
+		}
+	)
+
+	rows, err = utils.GetPostgrePool().Query(
+		context.Background(),
+		`
+SELECT
+	launcher_channel.name,
+	launcher_channel.description,
+	launcher_channel.public,
+	COALESCE(
+		array_agg(member.account_id ORDER BY member.account_id) FILTER (WHERE member.account_id IS NOT NULL),
+		'{}'::INTEGER[]
+	) AS "accounts"
+FROM launcher_channel
+LEFT JOIN account_launcher_channel AS member ON member.channel = launcher_channel.name
+GROUP BY launcher_channel.name
+ORDER BY launcher_channel.name
+`,
+	)
+	if err == nil {
+		channels, err = pgx.CollectRows(rows, pgx.RowToStructByName[launcherChannelMembers])
+	}
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+
+		fmt.Printf("Error getting launcher channels from DB: %s\n", err.Error())
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(response.ResponseErrorDatabase),
+		)
+
+		return
+	}
+
+	for _, channel := range channels {
+		channelsResponse.Channels = append(
+			channelsResponse.Channels,
+			response.LauncherChannel{
+				Name:        channel.Name,
+				Description: channel.Description,
+				Public:      channel.Public,
+				Accounts:    channel.Accounts,
+			},
+		)
+	}
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		channelsResponse,
+	)
+}
+
+func AdminSetAccountLauncherChannel(gc *gin.Context) {
+
+	var (
+		err error
+
+		ok bool
+
+		account int64
+
+		commandTag pgconn.CommandTag
+
+		channelRequest AdminLauncherChannelRequest
+	)
+
+	account, ok = getIDParam(gc, "account")
+	if !ok {
+		return
+	}
+
+	err = gc.BindJSON(&channelRequest)
+	if err != nil {
+		fmt.Println("Could not parse request body as PUT AdminSetAccountLauncherChannel")
+
+		return
+	}
+
+	if getLauncherChannel(channelRequest.Channel) == nil {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(response.ResponseErrorUnknownLauncherChannel),
+		)
+
+		return
+	}
+
+	commandTag, err = utils.GetPostgrePool().Exec(
+		context.Background(),
+		`
+INSERT INTO "account_launcher_channel" ("account_id", "channel")
+SELECT "id", $2 FROM "account" WHERE "id" = $1
+ON CONFLICT ("account_id") DO UPDATE SET "channel" = EXCLUDED."channel"
+`,
+		account,
+		channelRequest.Channel,
+	)
+	if err != nil {
+
+		fmt.Printf("Error assigning account %d to launcher channel: %s\n", account, err.Error())
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(response.ResponseErrorDatabase),
+		)
+
+		return
+	}
+
+	if commandTag.RowsAffected() == 0 {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(response.ResponseErrorUnknownAccount),
+		)
+
+		return
+	}
+
+	fmt.Printf("Account ID [%d] assigned to launcher channel [%s]\n", account, channelRequest.Channel)
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		response.DefaultResponse{
+			Status: response.ResponseErrorSuccess,
+		},
+	)
+}
+
+func AdminDeleteAccountLauncherChannel(gc *gin.Context) {
+
+	var (
+		err error
+
+		ok bool
+
+		account int64
+	)
+
+	account, ok = getIDParam(gc, "account")
+	if !ok {
+		return
+	}
+
+	_, err = utils.GetPostgrePool().Exec(
+		context.Background(),
+		`DELETE FROM "account_launcher_channel" WHERE "account_id" = $1`,
+		account,
+	)
+	if err != nil {
+
+		fmt.Printf("Error removing account %d from launcher channel: %s\n", account, err.Error())
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(response.ResponseErrorDatabase),
+		)
+
+		return
+	}
+
+	fmt.Printf("Account ID [%d] removed from launcher channel\n", account)
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		response.DefaultResponse{
+			Status: response.ResponseErrorSuccess,
+		},
+	)
+}
@@ -0,0 +1,219 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+
+	"PSF-LoginAPI/response"
+	"PSF-LoginAPI/utils"
+)
+
+type rolePermissions struct {
+	Name        string   `db:"name"`
+	Description string   `db:"description"`
+	Permissions []string `db:"permissions"`
+}
+
+func AdminGetRoles(gc *gin.Context) {
+
+	var (
+		err error
+
+		rows pgx.Rows
+
+		roles []rolePermissions
+
+		rolesResponse = response.RolesResponse{
+			DefaultResponse: response.DefaultResponse{
+				Status: response.ResponseErrorSuccess,
+			},
+			Roles: []response.Role{},
+		}
+	)
+
+	rows, err = utils.GetPostgrePool().Query(
+		context.Background(),
+		`
+SELECT
+	role.name,
+	role.description,
+	COALESCE(
+		array_agg(role_permission.permission ORDER BY role_permission.permission) FILTER (WHERE role_permission.permission IS NOT NULL),
+		'{}'::VARCHAR[]
+	) AS "permissions"
+FROM role
+LEFT JOIN role_permission ON role_permission.role = role.name
+GROUP BY role.name
+ORDER BY role.name
+`,
+	)
+	if err == nil {
+		roles, err = pgx.CollectRows(rows, pgx.RowToStructByName[rolePermissions])
+	}
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+
+		fmt.Printf("Error getting roles from DB: %s\n", err.Error())
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(response.ResponseErrorDatabase),
+		)
+
+		return
+	}
+
+	for _, role := range roles {
+		rolesResponse.Roles = append(
+			rolesResponse.Roles,
+			response.Role{
+				Name:        role.Name,
+				Description: role.Description,
+				Permissions: role.Permissions,
+			},
+		)
+	}
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		rolesResponse,
+	)
+}
+
+func AdminGetAccountRoles(gc *gin.Context) {
+
+	var (
+		ok bool
+
+		statusCode int
+
+		account int64
+
+		roles       []string
+		permissions []string
+	)
+
+	account, ok = getIDParam(gc, "account")
+	if !ok {
+		return
+	}
+
+	statusCode, roles, permissions = getAccountRoles(account)
+	if statusCode != response.ResponseErrorSuccess {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(statusCode),
+		)
+
+		return
+	}
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		response.AccountRolesResponse{
+			DefaultResponse: response.DefaultResponse{
+				Status: response.ResponseErrorSuccess,
+			},
+			Roles:       roles,
+			Permissions: permissions,
+		},
+	)
+}
+
+func AdminAddAccountRole(gc *gin.Context) {
+	changeAccountRole(
+		gc,
+		AuditEventRoleAdded,
+		`INSERT INTO "account_role" ("account_id", "role") VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+	)
+}
+
+func AdminRemoveAccountRole(gc *gin.Context) {
+	changeAccountRole(
+		gc,
+		AuditEventRoleRemoved,
+		`DELETE FROM "account_role" WHERE "account_id" = $1 AND "role" = $2`,
+	)
+}
+
+// runs the query with the account and role of the request and audits the change
+func changeAccountRole(gc *gin.Context, auditEvent string, query string) {
+
+	var (
+		err error
+
+		ok bool
+
+		accountExists bool
+		roleExists    bool
+
+		account int64
+
+		role = gc.Param("role")
+	)
+
+	account, ok = getIDParam(gc, "account")
+	if !ok {
+		return
+	}
+
+	err = utils.GetPostgrePool().QueryRow(
+		context.Background(),
+		`SELECT EXISTS (SELECT 1 FROM "account" WHERE "id" = $1), EXISTS (SELECT 1 FROM "role" WHERE "name" = $2)`,
+		account,
+		role,
+	).Scan(&accountExists, &roleExists)
+	if err == nil && accountExists && roleExists {
+		_, err = utils.GetPostgrePool().Exec(context.Background(), query, account, role)
+	}
+	if err != nil {
+
+		fmt.Printf("Error changing role [%s] of account %d: %s\n", role, account, err.Error())
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(response.ResponseErrorDatabase),
+		)
+
+		return
+	}
+
+	if !accountExists || !roleExists {
+
+		statusCode := response.ResponseErrorUnknownAccount
+		if !roleExists {
+			statusCode = response.ResponseErrorUnknownRole
+		}
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(statusCode),
+		)
+
+		return
+	}
+
+	fmt.Printf("Account ID [%d] %s [%s]\n", account, auditEvent, role)
+
+	writeAuditEvent(
+		gc,
+		account,
+		auditEvent,
+		map[string]interface{}{
+			"role":  role,
+			"admin": getAdminAccount(gc),
+		},
+	)
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		response.DefaultResponse{
+			Status: response.ResponseErrorSuccess,
+		},
+	)
+}
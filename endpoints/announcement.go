@@ -0,0 +1,576 @@
+package endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"PSF-LoginAPI/cache"
+	"PSF-LoginAPI/feed"
+	"PSF-LoginAPI/response"
+	"PSF-LoginAPI/utils"
+)
+
+const announcementQuery = `
+SELECT "id", "title", "body", "priority", "channels", "modes", "roles", "publish_at", "expire_at"
+FROM "announcement"
+%s
+ORDER BY "priority" DESC, "publish_at" DESC
+`
+
+type Announcement struct {
+	ID       int64  `db:"id"`
+	Title    string `db:"title"`
+	Body     string `db:"body"`
+	Priority int32  `db:"priority"`
+	// empty targets match everyone
+	Channels  []string   `db:"channels"`
+	Modes     []int64    `db:"modes"`
+	Roles     []string   `db:"roles"`
+	PublishAt time.Time  `db:"publish_at"`
+	ExpireAt  *time.Time `db:"expire_at"`
+}
+
+type AdminAnnouncementRequest struct {
+	Title    string   `json:"title" binding:"required"`
+	Body     string   `json:"body"`
+	Priority int32    `json:"priority"`
+	Channels []string `json:"channels"`
+	Modes    []int64  `json:"modes"`
+	Roles    []string `json:"roles"`
+	// unix time, now if not set
+	PublishAt int64 `json:"publishAt"`
+	// unix time, never if not set
+	ExpireAt int64 `json:"expireAt"`
+}
+
+// announcements that did not expire yet
+var announcementCache = cache.New[int, []Announcement]("announcement", utils.GetCacheTTL())
+
+func (announcement *Announcement) isVisible(now time.Time, channel string, mode int64, hasMode bool, roles []string) bool {
+
+	if now.Before(announcement.PublishAt) || (announcement.ExpireAt != nil && !now.Before(*announcement.ExpireAt)) {
+		return false
+	}
+
+	if len(announcement.Channels) > 0 && !containsString(announcement.Channels, channel) {
+		return false
+	}
+
+	if len(announcement.Modes) > 0 && (!hasMode || !containsInt64(announcement.Modes, mode)) {
+		return false
+	}
+
+	if len(announcement.Roles) > 0 {
+
+		for _, role := range roles {
+			if containsString(announcement.Roles, role) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// Announcements lists the announcements visible to the caller right now.
+// The mode is taken from the token or the mode query parameter.
+func Announcements(gc *gin.Context) {
+
+	var (
+		err error
+
+		statusCode int
+
+		etag    string
+		mode    int64
+		hasMode bool
+
+		announcements []Announcement
+
+		now     = time.Now()
+		channel = ResolveLauncherChannel(gc)
+
+		roles, _ = getClaimsPermissions(gc)
+
+		announcementsResponse = response.AnnouncementsResponse{
+			DefaultResponse: response.DefaultResponse{
+				Status: response.ResponseErrorSuccess,
+			},
+			Announcements: []response.Announcement{},
+		}
+	)
+
+	if pClaims, exists := gc.Get("claims"); exists {
+		mode, err = pClaims.(jwt.MapClaims)["mode"].(json.Number).Int64()
+		hasMode = err == nil
+	}
+
+	if gc.Query("mode") != "" {
+
+		mode, err = strconv.ParseInt(gc.Query("mode"), 10, 64)
+		if err != nil {
+			gc.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+
+		hasMode = true
+	}
+
+	// callers without token are players
+	if len(roles) == 0 {
+		roles = []string{utils.RolePlayer}
+	}
+
+	statusCode, announcements = getAnnouncements()
+	if statusCode != response.ResponseErrorSuccess {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(statusCode),
+		)
+
+		return
+	}
+
+	for i := range announcements {
+		if announcements[i].isVisible(now, channel, mode, hasMode, roles) {
+			announcementsResponse.Announcements = append(
+				announcementsResponse.Announcements,
+				createAnnouncement(&announcements[i]),
+			)
+		}
+	}
+
+	etag, err = createAnnouncementsETag(announcementsResponse.Announcements)
+	if err == nil {
+
+		gc.Header("ETag", etag)
+		gc.Header("Cache-Control", "no-cache")
+
+		if utils.MatchesETag(gc.GetHeader("If-None-Match"), etag) {
+			gc.Status(http.StatusNotModified)
+			return
+		}
+	}
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		announcementsResponse,
+	)
+}
+
+func createAnnouncementsETag(announcements []response.Announcement) (string, error) {
+
+	encoded, err := json.Marshal(announcements)
+	if err != nil {
+		return "", err
+	}
+
+	return utils.CreateETag(string(encoded)), nil
+}
+
+func createAnnouncement(announcement *Announcement) response.Announcement {
+
+	var (
+		expireAt int64
+	)
+
+	if announcement.ExpireAt != nil {
+		expireAt = announcement.ExpireAt.Unix()
+	}
+
+	return response.Announcement{
+		ID:        announcement.ID,
+		Title:     announcement.Title,
+		Body:      announcement.Body,
+		Priority:  announcement.Priority,
+		Channels:  announcement.Channels,
+		Modes:     announcement.Modes,
+		Roles:     announcement.Roles,
+		PublishAt: announcement.PublishAt.Unix(),
+		ExpireAt:  expireAt,
+	}
+}
+
+func getAnnouncements() (statusCode int, announcements []Announcement) {
+
+	announcements = announcementCache.Get(
+		0,
+		func(int) ([]Announcement, bool) {
+			statusCode, announcements = loadAnnouncements(`WHERE "expire_at" IS NULL OR "expire_at" > NOW()`)
+			return announcements, statusCode == response.ResponseErrorSuccess
+		},
+	)
+
+	return
+}
+
+func loadAnnouncements(condition string) (statusCode int, announcements []Announcement) {
+
+	var (
+		err error
+
+		rows pgx.Rows
+	)
+
+	rows, err = utils.GetPostgrePool().Query(
+		context.Background(),
+		fmt.Sprintf(announcementQuery, condition),
+	)
+	if err != nil {
+		statusCode = response.ResponseErrorDatabase
+
+		fmt.Printf("Error querying announcements from DB: %s\n", err.Error())
+
+		return
+	}
+
+	announcements, err = pgx.CollectRows(rows, pgx.RowToStructByName[Announcement])
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		statusCode = response.ResponseErrorDatabase
+
+		fmt.Printf("Error parsing announcements from DB: %s\n", err.Error())
+
+		return
+	}
+
+	return
+}
+
+// AdminGetAnnouncements lists all announcements including scheduled and expired ones
+func AdminGetAnnouncements(gc *gin.Context) {
+
+	var (
+		statusCode int
+
+		announcements []Announcement
+
+		announcementsResponse = response.AnnouncementsResponse{
+			DefaultResponse: response.DefaultResponse{
+				Status: response.ResponseErrorSuccess,
+			},
+			Announcements: []response.Announcement{},
+		}
+	)
+
+	statusCode, announcements = loadAnnouncements("")
+	if statusCode != response.ResponseErrorSuccess {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(statusCode),
+		)
+
+		return
+	}
+
+	for i := range announcements {
+		announcementsResponse.Announcements = append(
+			announcementsResponse.Announcements,
+			createAnnouncement(&announcements[i]),
+		)
+	}
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		announcementsResponse,
+	)
+}
+
+func AdminCreateAnnouncement(gc *gin.Context) {
+
+	var (
+		err error
+
+		id int64
+
+		announcementRequest AdminAnnouncementRequest
+	)
+
+	err = gc.BindJSON(&announcementRequest)
+	if err != nil {
+		fmt.Println("Could not parse request body as POST AdminCreateAnnouncement")
+
+		return
+	}
+
+	err = utils.GetPostgrePool().QueryRow(
+		context.Background(),
+		`
+INSERT INTO "announcement" ("title", "body", "priority", "channels", "modes", "roles", "publish_at", "expire_at")
+VALUES ($1, $2, $3, $4, $5, $6, COALESCE(to_timestamp(NULLIF($7::BIGINT, 0)), NOW()), to_timestamp(NULLIF($8::BIGINT, 0)))
+RETURNING "id"
+`,
+		announcementRequest.Title,
+		announcementRequest.Body,
+		announcementRequest.Priority,
+		emptyIfNil(announcementRequest.Channels),
+		emptyIfNil(announcementRequest.Modes),
+		emptyIfNil(announcementRequest.Roles),
+		announcementRequest.PublishAt,
+		announcementRequest.ExpireAt,
+	).Scan(&id)
+	if err != nil {
+
+		fmt.Printf("Error creating announcement: %s\n", err.Error())
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(response.ResponseErrorDatabase),
+		)
+
+		return
+	}
+
+	fmt.Printf("Announcement %d created by account ID [%d]\n", id, getAdminAccount(gc))
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		response.IDResponse{
+			DefaultResponse: response.DefaultResponse{
+				Status: response.ResponseErrorSuccess,
+			},
+			ID: id,
+		},
+	)
+}
+
+func AdminUpdateAnnouncement(gc *gin.Context) {
+
+	var (
+		err error
+
+		ok bool
+
+		id int64
+
+		commandTag pgconn.CommandTag
+
+		announcementRequest AdminAnnouncementRequest
+	)
+
+	id, ok = getIDParam(gc, "id")
+	if !ok {
+		return
+	}
+
+	err = gc.BindJSON(&announcementRequest)
+	if err != nil {
+		fmt.Println("Could not parse request body as PUT AdminUpdateAnnouncement")
+
+		return
+	}
+
+	commandTag, err = utils.GetPostgrePool().Exec(
+		context.Background(),
+		`
+UPDATE "announcement" SET
+	"title" = $2,
+	"body" = $3,
+	"priority" = $4,
+	"channels" = $5,
+	"modes" = $6,
+	"roles" = $7,
+	"publish_at" = COALESCE(to_timestamp(NULLIF($8::BIGINT, 0)), "publish_at"),
+	"expire_at" = to_timestamp(NULLIF($9::BIGINT, 0)),
+	"updated_at" = NOW()
+WHERE "id" = $1
+`,
+		id,
+		announcementRequest.Title,
+		announcementRequest.Body,
+		announcementRequest.Priority,
+		emptyIfNil(announcementRequest.Channels),
+		emptyIfNil(announcementRequest.Modes),
+		emptyIfNil(announcementRequest.Roles),
+		announcementRequest.PublishAt,
+		announcementRequest.ExpireAt,
+	)
+	if err == nil && commandTag.RowsAffected() == 0 {
+		gc.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+
+		fmt.Printf("Error updating announcement %d: %s\n", id, err.Error())
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(response.ResponseErrorDatabase),
+		)
+
+		return
+	}
+
+	fmt.Printf("Announcement %d updated by account ID [%d]\n", id, getAdminAccount(gc))
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		response.DefaultResponse{
+			Status: response.ResponseErrorSuccess,
+		},
+	)
+}
+
+func AdminDeleteAnnouncement(gc *gin.Context) {
+
+	var (
+		err error
+
+		ok bool
+
+		id int64
+	)
+
+	id, ok = getIDParam(gc, "id")
+	if !ok {
+		return
+	}
+
+	_, err = utils.GetPostgrePool().Exec(
+		context.Background(),
+		`DELETE FROM "announcement" WHERE "id" = $1`,
+		id,
+	)
+	if err != nil {
+
+		fmt.Printf("Error deleting announcement %d: %s\n", id, err.Error())
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(response.ResponseErrorDatabase),
+		)
+
+		return
+	}
+
+	fmt.Printf("Announcement %d deleted by account ID [%d]\n", id, getAdminAccount(gc))
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		response.DefaultResponse{
+			Status: response.ResponseErrorSuccess,
+		},
+	)
+}
+
+// AdminImportAnnouncements imports the RSS or Atom feed in the request body
+func AdminImportAnnouncements(gc *gin.Context) {
+
+	var (
+		err error
+
+		imported int
+	)
+
+	imported, err = ImportAnnouncements(gc.Request.Body)
+	if err != nil {
+
+		fmt.Printf("Error importing announcements: %s\n", err.Error())
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponseWithText(response.ResponseErrorInvalidFeed, err.Error()),
+		)
+
+		return
+	}
+
+	fmt.Printf("%d announcements imported by account ID [%d]\n", imported, getAdminAccount(gc))
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		response.ImportResponse{
+			DefaultResponse: response.DefaultResponse{
+				Status: response.ResponseErrorSuccess,
+			},
+			Imported: imported,
+		},
+	)
+}
+
+// ImportAnnouncements adds the items of an RSS or Atom feed as announcements,
+// items imported before are updated
+func ImportAnnouncements(reader io.Reader) (imported int, err error) {
+
+	var (
+		items []feed.Item
+
+		batch = &pgx.Batch{}
+	)
+
+	items, err = feed.Parse(reader)
+	if err != nil {
+		return
+	}
+
+	for _, item := range items {
+		batch.Queue(
+			`
+INSERT INTO "announcement" ("title", "body", "publish_at", "guid")
+VALUES ($1, $2, $3, $4)
+ON CONFLICT ("guid") DO UPDATE SET
+	"title" = EXCLUDED."title",
+	"body" = EXCLUDED."body",
+	"publish_at" = EXCLUDED."publish_at",
+	"updated_at" = NOW()
+`,
+			item.Title,
+			item.Body,
+			item.PublishedAt,
+			item.GUID,
+		)
+	}
+
+	err = utils.GetPostgrePool().SendBatch(context.Background(), batch).Close()
+	if err != nil {
+		return
+	}
+
+	return len(items), nil
+}
+
+func containsString(values []string, value string) bool {
+
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsInt64(values []int64, value int64) bool {
+
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// nil slices are sent to the DB as NULL
+func emptyIfNil[T any](values []T) []T {
+
+	if values == nil {
+		return []T{}
+	}
+
+	return values
+}
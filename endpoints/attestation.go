@@ -0,0 +1,171 @@
+package endpoints
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"PSF-LoginAPI/response"
+	"PSF-LoginAPI/utils"
+)
+
+// how long a launcher has to sign a challenge and log in with it
+const challengeLifetime = 2 * time.Minute
+
+// LauncherAttestation is a server issued challenge signed with the key embedded in the launcher build
+type LauncherAttestation struct {
+	Challenge string `json:"challenge" binding:"required"`
+	// base64 encoded Ed25519 signature of the challenge
+	Signature string `json:"signature" binding:"required"`
+}
+
+// challenges already used for a login, by expiry
+var (
+	usedChallengesMutex sync.Mutex
+	usedChallenges      = make(map[string]time.Time)
+)
+
+func Challenge(gc *gin.Context) {
+
+	var (
+		err error
+
+		challenge string
+
+		nonce = make([]byte, 32)
+	)
+
+	_, err = rand.Read(nonce)
+	if err == nil {
+		challenge, err = utils.GenerateTokenWithLifetime(
+			&jwt.MapClaims{
+				"purpose": utils.TokenPurposeAttestation,
+				"nonce":   base64.StdEncoding.EncodeToString(nonce),
+			},
+			challengeLifetime,
+		)
+	}
+	if err != nil {
+
+		fmt.Printf("Challenge creation failed: %s\n", err.Error())
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(response.ResponseErrorInternalTokenCreationFailed),
+		)
+
+		return
+	}
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		response.ChallengeResponse{
+			DefaultResponse: response.DefaultResponse{
+				Status: response.ResponseErrorSuccess,
+			},
+			Challenge: challenge,
+		},
+	)
+}
+
+// returns the launcher release and artifact whose key signed the challenge, nil if none did
+func getLauncherFromAttestation(attestation *LauncherAttestation) (statusCode int, launcher *Launcher, artifact *LauncherArtifact) {
+
+	var (
+		err error
+
+		signature []byte
+		publicKey []byte
+
+		launchers []Launcher
+
+		decodedToken *jwt.Token
+		claims       *jwt.MapClaims
+	)
+
+	decodedToken, claims, err = utils.ParseToken(attestation.Challenge)
+	if err != nil || !decodedToken.Valid || (*claims)["purpose"] != utils.TokenPurposeAttestation {
+		fmt.Println("Launcher attestation with invalid challenge")
+		return
+	}
+
+	signature, err = base64.StdEncoding.DecodeString(attestation.Signature)
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		fmt.Println("Launcher attestation with invalid signature encoding")
+		return
+	}
+
+	statusCode, launchers = getLaunchers()
+	if statusCode != response.ResponseErrorSuccess {
+		return
+	}
+
+	// inactive launchers are checked too, so they can be told they are no longer supported
+	for i := range launchers {
+
+		for j := range launchers[i].Artifacts {
+
+			publicKey, err = base64.StdEncoding.DecodeString(launchers[i].Artifacts[j].PublicKey)
+			if err != nil || len(publicKey) != ed25519.PublicKeySize {
+				continue
+			}
+
+			if ed25519.Verify(publicKey, []byte(attestation.Challenge), signature) {
+				launcher, artifact = &launchers[i], &launchers[i].Artifacts[j]
+				break
+			}
+		}
+
+		if launcher != nil {
+			break
+		}
+	}
+
+	if launcher == nil {
+		fmt.Println("Launcher attestation not signed by any active launcher")
+		return
+	}
+
+	// challenges can only be used once
+	if !useChallenge(attestation.Challenge, challengeLifetime) {
+
+		fmt.Println("Launcher attestation with already used challenge")
+
+		return statusCode, nil, nil
+	}
+
+	return
+}
+
+// marks the challenge as used, false if it was used before
+func useChallenge(challenge string, lifetime time.Duration) bool {
+
+	var (
+		now = time.Now()
+	)
+
+	usedChallengesMutex.Lock()
+	defer usedChallengesMutex.Unlock()
+
+	// forget challenges that expired, they are rejected by their expiry anyway
+	for usedChallenge, expiresAt := range usedChallenges {
+		if now.After(expiresAt) {
+			delete(usedChallenges, usedChallenge)
+		}
+	}
+
+	if _, used := usedChallenges[challenge]; used {
+		return false
+	}
+
+	usedChallenges[challenge] = now.Add(lifetime)
+
+	return true
+}
@@ -0,0 +1,60 @@
+package endpoints
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"PSF-LoginAPI/utils"
+)
+
+// Audit events
+const (
+	AuditEventLogin       = "login"
+	AuditEventRoleAdded   = "role_added"
+	AuditEventRoleRemoved = "role_removed"
+	AuditEventBanned      = "banned"
+	AuditEventBanLifted   = "ban_lifted"
+
+	AuditEventPasswordChanged = "password_changed"
+	AuditEventRegistered      = "registered"
+	AuditEventInviteRedeemed  = "invite_redeemed"
+
+	AuditEventEmailChanged           = "email_changed"
+	AuditEventEmailVerified          = "email_verified"
+	AuditEventPasswordResetRequested = "password_reset_requested"
+	AuditEventPasswordReset          = "password_reset"
+
+	AuditEventTwoFactorEnabled  = "two_factor_enabled"
+	AuditEventTwoFactorDisabled = "two_factor_disabled"
+	AuditEventTwoFactorFailed   = "two_factor_failed"
+	AuditEventRecoveryCodeUsed  = "recovery_code_used"
+
+	AuditEventDeviceAdded   = "device_added"
+	AuditEventDeviceRevoked = "device_revoked"
+)
+
+// writes an audit event for the account, failing to do so does not fail the request
+func writeAuditEvent(gc *gin.Context, account int64, event string, details map[string]interface{}) {
+
+	var (
+		err error
+	)
+
+	if details == nil {
+		details = map[string]interface{}{}
+	}
+
+	_, err = utils.GetPostgrePool().Exec(
+		context.Background(),
+		`INSERT INTO "audit_log" ("account_id", "event", "ip", "details") VALUES ($1, $2, NULLIF($3, '')::INET, $4)`,
+		account,
+		event,
+		gc.ClientIP(),
+		details,
+	)
+	if err != nil {
+		fmt.Printf("Error writing audit event [%s] for account %d: %s\n", event, account, err.Error())
+	}
+}
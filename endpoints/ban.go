@@ -0,0 +1,530 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+
+	"PSF-LoginAPI/response"
+	"PSF-LoginAPI/utils"
+)
+
+const banQuery = `
+SELECT "id", "account_id", "cidr"::TEXT AS "cidr", "reason", "issued_by", "starts_at", "expires_at", "lifted_at", "lifted_by"
+FROM "ban"
+WHERE %s
+ORDER BY %s
+`
+
+// bans in effect right now
+const activeBanCondition = `"lifted_at" IS NULL AND "starts_at" <= NOW() AND ("expires_at" IS NULL OR "expires_at" > NOW())`
+
+type Ban struct {
+	ID int64 `db:"id"`
+	// nil for IP bans
+	AccountID *int64 `db:"account_id"`
+	// nil for account bans
+	CIDR   *string `db:"cidr"`
+	Reason string  `db:"reason"`
+	// nil for bans issued with the admin API key
+	IssuedBy *int64    `db:"issued_by"`
+	StartsAt time.Time `db:"starts_at"`
+	// nil for permanent bans
+	ExpiresAt *time.Time `db:"expires_at"`
+	LiftedAt  *time.Time `db:"lifted_at"`
+	LiftedBy  *int64     `db:"lifted_by"`
+}
+
+type AdminBanRequest struct {
+	Account *int64 `json:"account"`
+	// single address or CIDR range
+	CIDR   *string `json:"cidr"`
+	Reason string  `json:"reason" binding:"required"`
+	// unix time, now if not set
+	StartsAt int64 `json:"startsAt"`
+	// unix time, permanent if not set
+	ExpiresAt int64 `json:"expiresAt"`
+}
+
+func (ban *Ban) isActive(now time.Time) bool {
+	return ban.LiftedAt == nil && !now.Before(ban.StartsAt) && (ban.ExpiresAt == nil || now.Before(*ban.ExpiresAt))
+}
+
+func createBan(ban *Ban) response.Ban {
+
+	var (
+		expiresAt int64
+		liftedAt  int64
+	)
+
+	if ban.ExpiresAt != nil {
+		expiresAt = ban.ExpiresAt.Unix()
+	}
+
+	if ban.LiftedAt != nil {
+		liftedAt = ban.LiftedAt.Unix()
+	}
+
+	return response.Ban{
+		ID:        ban.ID,
+		Account:   ban.AccountID,
+		CIDR:      ban.CIDR,
+		Reason:    ban.Reason,
+		IssuedBy:  ban.IssuedBy,
+		StartsAt:  ban.StartsAt.Unix(),
+		ExpiresAt: expiresAt,
+		LiftedAt:  liftedAt,
+		LiftedBy:  ban.LiftedBy,
+		Active:    ban.isActive(time.Now()),
+	}
+}
+
+// writes the ban response if the account or the IP of the request is banned,
+// returns false if the request has to stop
+func checkBan(gc *gin.Context, account int64) bool {
+
+	var (
+		statusCode int
+
+		ban *Ban
+
+		ip = gc.ClientIP()
+	)
+
+	statusCode, ban = getActiveBan(account, ip)
+
+	return rejectBan(gc, statusCode, ban, fmt.Sprintf("Account ID [%d] from %s", account, ip))
+}
+
+// writes the ban response if the IP of the request is banned, used before the account is known,
+// returns false if the request has to stop
+func checkAddressBan(gc *gin.Context) bool {
+
+	var (
+		statusCode int
+
+		ban *Ban
+
+		ip = gc.ClientIP()
+	)
+
+	statusCode, ban = getActiveAddressBan(ip)
+
+	return rejectBan(gc, statusCode, ban, ip)
+}
+
+// writes the error or ban response, returns false if the request has to stop
+func rejectBan(gc *gin.Context, statusCode int, ban *Ban, subject string) bool {
+
+	if statusCode != response.ResponseErrorSuccess {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(statusCode),
+		)
+
+		return false
+	}
+
+	if ban == nil {
+		return true
+	}
+
+	statusCode = response.ResponseErrorAccountBanned
+	if ban.AccountID == nil {
+		statusCode = response.ResponseErrorAddressBanned
+	}
+
+	fmt.Printf("%s rejected by ban %d\n", subject, ban.ID)
+
+	banResponse := response.BanResponse{
+		DefaultResponse: response.DefaultResponse{
+			Status: statusCode,
+		},
+		Reason: ban.Reason,
+	}
+
+	if ban.ExpiresAt != nil {
+		banResponse.EndsAt = ban.ExpiresAt.Unix()
+	}
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		banResponse,
+	)
+
+	return false
+}
+
+// returns the active ban of the account or IP that lasts the longest, nil if there is none
+func getActiveBan(account int64, ip string) (statusCode int, ban *Ban) {
+
+	var (
+		bans []Ban
+	)
+
+	statusCode, bans = loadBans(
+		activeBanCondition+` AND ("account_id" = $1 OR "cidr" >>= NULLIF($2, '')::INET)`,
+		`"expires_at" DESC NULLS FIRST`,
+		account,
+		ip,
+	)
+	if statusCode != response.ResponseErrorSuccess || len(bans) == 0 {
+		return
+	}
+
+	ban = &bans[0]
+
+	return
+}
+
+// returns the active IP ban of the address that lasts the longest, nil if there is none
+func getActiveAddressBan(ip string) (statusCode int, ban *Ban) {
+
+	var (
+		bans []Ban
+	)
+
+	statusCode, bans = loadBans(
+		activeBanCondition+` AND "cidr" >>= NULLIF($1, '')::INET`,
+		`"expires_at" DESC NULLS FIRST`,
+		ip,
+	)
+	if statusCode != response.ResponseErrorSuccess || len(bans) == 0 {
+		return
+	}
+
+	ban = &bans[0]
+
+	return
+}
+
+func loadBans(condition string, order string, args ...interface{}) (statusCode int, bans []Ban) {
+
+	var (
+		err error
+
+		rows pgx.Rows
+	)
+
+	rows, err = utils.GetPostgrePool().Query(
+		context.Background(),
+		fmt.Sprintf(banQuery, condition, order),
+		args...,
+	)
+	if err != nil {
+		statusCode = response.ResponseErrorDatabase
+
+		fmt.Printf("Error querying bans from DB: %s\n", err.Error())
+
+		return
+	}
+
+	bans, err = pgx.CollectRows(rows, pgx.RowToStructByName[Ban])
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		statusCode = response.ResponseErrorDatabase
+
+		fmt.Printf("Error parsing bans from DB: %s\n", err.Error())
+
+		return
+	}
+
+	return
+}
+
+// normalizes a single address or CIDR range, returns false if it is neither
+func parseBanCIDR(value string) (cidr string, ok bool) {
+
+	var (
+		err error
+
+		ip      net.IP
+		network *net.IPNet
+	)
+
+	_, network, err = net.ParseCIDR(value)
+	if err == nil {
+		return network.String(), true
+	}
+
+	ip = net.ParseIP(value)
+	if ip == nil {
+		return "", false
+	}
+
+	if ip.To4() != nil {
+		return ip.String() + "/32", true
+	}
+
+	return ip.String() + "/128", true
+}
+
+// AdminGetBans lists bans newest first, only active ones if the active query parameter is true
+// and only the ones of an account if the account query parameter is set
+func AdminGetBans(gc *gin.Context) {
+
+	var (
+		err error
+
+		statusCode int
+
+		account int64
+
+		bans []Ban
+
+		condition = "TRUE"
+		args      []interface{}
+
+		bansResponse = response.BansResponse{
+			DefaultResponse: response.DefaultResponse{
+				Status: response.ResponseErrorSuccess,
+			},
+			Bans: []response.Ban{},
+		}
+	)
+
+	if gc.Query("active") == "true" {
+		condition = activeBanCondition
+	}
+
+	if gc.Query("account") != "" {
+
+		account, err = strconv.ParseInt(gc.Query("account"), 10, 64)
+		if err != nil {
+			gc.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+
+		condition += ` AND "account_id" = $1`
+		args = append(args, account)
+	}
+
+	statusCode, bans = loadBans(condition, `"starts_at" DESC`, args...)
+	if statusCode != response.ResponseErrorSuccess {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(statusCode),
+		)
+
+		return
+	}
+
+	for i := range bans {
+		bansResponse.Bans = append(bansResponse.Bans, createBan(&bans[i]))
+	}
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		bansResponse,
+	)
+}
+
+func AdminCreateBan(gc *gin.Context) {
+
+	var (
+		err error
+
+		ok bool
+
+		id int64
+
+		accountExists = true
+
+		cidr *string
+
+		banRequest AdminBanRequest
+
+		admin = getAdminAccount(gc)
+	)
+
+	err = gc.BindJSON(&banRequest)
+	if err != nil {
+		fmt.Println("Could not parse request body as POST AdminCreateBan")
+
+		return
+	}
+
+	// ban has to target something
+	if banRequest.Account == nil && banRequest.CIDR == nil {
+		gc.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	// bans starting in the past are in effect from now on
+	effectiveStart := time.Now().Unix()
+	if banRequest.StartsAt > effectiveStart {
+		effectiveStart = banRequest.StartsAt
+	}
+
+	// ban has to be in effect at some point
+	if banRequest.ExpiresAt != 0 && banRequest.ExpiresAt <= effectiveStart {
+		gc.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	if banRequest.CIDR != nil {
+
+		var normalized string
+
+		normalized, ok = parseBanCIDR(*banRequest.CIDR)
+		if !ok {
+			gc.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+
+		cidr = &normalized
+	}
+
+	if banRequest.Account != nil {
+		err = utils.GetPostgrePool().QueryRow(
+			context.Background(),
+			`SELECT EXISTS (SELECT 1 FROM "account" WHERE "id" = $1)`,
+			*banRequest.Account,
+		).Scan(&accountExists)
+	}
+	if err == nil && accountExists {
+		err = utils.GetPostgrePool().QueryRow(
+			context.Background(),
+			`
+INSERT INTO "ban" ("account_id", "cidr", "reason", "issued_by", "starts_at", "expires_at")
+VALUES ($1, $2::CIDR, $3, NULLIF($4::INTEGER, 0), COALESCE(to_timestamp(NULLIF($5::BIGINT, 0)), NOW()), to_timestamp(NULLIF($6::BIGINT, 0)))
+RETURNING "id"
+`,
+			banRequest.Account,
+			cidr,
+			banRequest.Reason,
+			admin,
+			banRequest.StartsAt,
+			banRequest.ExpiresAt,
+		).Scan(&id)
+	}
+	if err != nil {
+
+		fmt.Printf("Error creating ban: %s\n", err.Error())
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(response.ResponseErrorDatabase),
+		)
+
+		return
+	}
+
+	if !accountExists {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(response.ResponseErrorUnknownAccount),
+		)
+
+		return
+	}
+
+	fmt.Printf("Ban %d created by account ID [%d]\n", id, admin)
+
+	if banRequest.Account != nil {
+		writeAuditEvent(
+			gc,
+			*banRequest.Account,
+			AuditEventBanned,
+			map[string]interface{}{
+				"ban":       id,
+				"reason":    banRequest.Reason,
+				"expiresAt": banRequest.ExpiresAt,
+				"admin":     admin,
+			},
+		)
+	}
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		response.IDResponse{
+			DefaultResponse: response.DefaultResponse{
+				Status: response.ResponseErrorSuccess,
+			},
+			ID: id,
+		},
+	)
+}
+
+// AdminLiftBan ends a ban early, the ban is kept for the ban history of the account
+func AdminLiftBan(gc *gin.Context) {
+
+	var (
+		err error
+
+		ok bool
+
+		id int64
+
+		account *int64
+
+		admin = getAdminAccount(gc)
+	)
+
+	id, ok = getIDParam(gc, "id")
+	if !ok {
+		return
+	}
+
+	err = utils.GetPostgrePool().QueryRow(
+		context.Background(),
+		`
+UPDATE "ban"
+SET "lifted_at" = NOW(), "lifted_by" = NULLIF($2::INTEGER, 0)
+WHERE "id" = $1 AND "lifted_at" IS NULL
+RETURNING "account_id"
+`,
+		id,
+		admin,
+	).Scan(&account)
+	if errors.Is(err, pgx.ErrNoRows) {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(response.ResponseErrorUnknownBan),
+		)
+
+		return
+	}
+	if err != nil {
+
+		fmt.Printf("Error lifting ban %d: %s\n", id, err.Error())
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(response.ResponseErrorDatabase),
+		)
+
+		return
+	}
+
+	fmt.Printf("Ban %d lifted by account ID [%d]\n", id, admin)
+
+	if account != nil {
+		writeAuditEvent(
+			gc,
+			*account,
+			AuditEventBanLifted,
+			map[string]interface{}{
+				"ban":   id,
+				"admin": admin,
+			},
+		)
+	}
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		response.DefaultResponse{
+			Status: response.ResponseErrorSuccess,
+		},
+	)
+}
@@ -0,0 +1,171 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+
+	"PSF-LoginAPI/cache"
+	"PSF-LoginAPI/response"
+	"PSF-LoginAPI/utils"
+)
+
+// channel of launchers without an assigned or requested channel
+const DefaultLauncherChannel = "stable"
+
+type LauncherChannel struct {
+	Name        string `db:"name"`
+	Description string `db:"description"`
+	Public      bool   `db:"public"`
+}
+
+var launcherChannelCache = cache.New[int, []LauncherChannel]("launcher", utils.GetCacheTTL())
+
+// channel per account, empty if the account is not assigned to a channel
+var accountLauncherChannelCache = cache.New[int64, string]("launcher_channel", utils.GetCacheTTL())
+
+// ResolveLauncherChannel returns the channel of the caller.
+// Launchers can request a public channel with the X-Launcher-Channel header,
+// other channels are only available to accounts an admin assigned to them.
+func ResolveLauncherChannel(gc *gin.Context) string {
+
+	var (
+		accountChannel string
+
+		requestedChannel = gc.GetHeader("X-Launcher-Channel")
+
+		account, authenticated = getClaimsAccount(gc)
+	)
+
+	if authenticated {
+		_, accountChannel = getAccountLauncherChannel(account)
+	}
+
+	// accounts use their assigned channel unless they request another one
+	if requestedChannel == "" {
+		requestedChannel = accountChannel
+	}
+
+	if requestedChannel == "" {
+		return DefaultLauncherChannel
+	}
+
+	if requestedChannel == accountChannel {
+		return accountChannel
+	}
+
+	channel := getLauncherChannel(requestedChannel)
+	if channel != nil && channel.Public {
+		return channel.Name
+	}
+
+	return DefaultLauncherChannel
+}
+
+// returns the channel with the name, nil if it does not exist
+func getLauncherChannel(name string) *LauncherChannel {
+
+	_, channels := getLauncherChannels()
+
+	for i := range channels {
+		if channels[i].Name == name {
+			return &channels[i]
+		}
+	}
+
+	return nil
+}
+
+func getLauncherChannels() (statusCode int, channels []LauncherChannel) {
+
+	channels = launcherChannelCache.Get(
+		0,
+		func(int) ([]LauncherChannel, bool) {
+			statusCode, channels = loadLauncherChannels()
+			return channels, statusCode == response.ResponseErrorSuccess
+		},
+	)
+
+	return
+}
+
+func loadLauncherChannels() (statusCode int, channels []LauncherChannel) {
+
+	var (
+		err error
+
+		rows pgx.Rows
+	)
+
+	rows, err = utils.GetPostgrePool().Query(
+		context.Background(),
+		`SELECT "name", "description", "public" FROM "launcher_channel" ORDER BY "name"`,
+	)
+	if err != nil {
+		statusCode = response.ResponseErrorDatabase
+
+		fmt.Printf("Error querying launcher channels from DB: %s\n", err.Error())
+
+		return
+	}
+
+	channels, err = pgx.CollectRows(rows, pgx.RowToStructByName[LauncherChannel])
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		statusCode = response.ResponseErrorDatabase
+
+		fmt.Printf("Error parsing launcher channels from DB: %s\n", err.Error())
+
+		return
+	}
+
+	return
+}
+
+func getAccountLauncherChannel(account int64) (statusCode int, channel string) {
+
+	channel = accountLauncherChannelCache.Get(
+		account,
+		func(account int64) (string, bool) {
+			statusCode, channel = loadAccountLauncherChannel(account)
+			return channel, statusCode == response.ResponseErrorSuccess
+		},
+	)
+
+	return
+}
+
+func loadAccountLauncherChannel(account int64) (statusCode int, channel string) {
+
+	var (
+		err error
+
+		rows pgx.Rows
+	)
+
+	rows, err = utils.GetPostgrePool().Query(
+		context.Background(),
+		`SELECT "channel" FROM "account_launcher_channel" WHERE "account_id" = $1`,
+		account,
+	)
+	if err != nil {
+		statusCode = response.ResponseErrorDatabase
+
+		fmt.Printf("Error querying launcher channel of account %d from DB: %s\n", account, err.Error())
+
+		return
+	}
+
+	channel, err = pgx.CollectOneRow(rows, pgx.RowTo[string])
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		statusCode = response.ResponseErrorDatabase
+
+		fmt.Printf("Error parsing launcher channel of account %d from DB: %s\n", account, err.Error())
+
+		return
+	}
+
+	return
+}
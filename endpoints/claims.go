@@ -0,0 +1,101 @@
+package endpoints
+
+import (
+	"encoding/json"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"PSF-LoginAPI/utils"
+)
+
+// claims set by the token creation, everything else is carried over to follow-up tokens
+var registeredClaims = map[string]bool{
+	"iss": true,
+	"iat": true,
+	"nbf": true,
+	"exp": true,
+}
+
+// returns the account of the request claims, false for requests without valid token
+func getClaimsAccount(gc *gin.Context) (account int64, ok bool) {
+
+	var (
+		err error
+
+		number json.Number
+
+		pClaims, exists = gc.Get("claims")
+	)
+
+	if !exists {
+		return
+	}
+
+	number, ok = pClaims.(jwt.MapClaims)["account"].(json.Number)
+	if !ok {
+		return
+	}
+
+	account, err = number.Int64()
+
+	return account, err == nil
+}
+
+// returns the mode of the request claims, false for requests without valid token
+func getClaimsMode(gc *gin.Context) (mode int64, ok bool) {
+
+	var (
+		err error
+
+		number json.Number
+
+		pClaims, exists = gc.Get("claims")
+	)
+
+	if !exists {
+		return
+	}
+
+	number, ok = pClaims.(jwt.MapClaims)["mode"].(json.Number)
+	if !ok {
+		return
+	}
+
+	mode, err = number.Int64()
+
+	return mode, err == nil
+}
+
+// returns the roles and permissions of the request claims, none for requests without valid token
+func getClaimsPermissions(gc *gin.Context) (roles []string, permissions []string) {
+
+	var (
+		pClaims, exists = gc.Get("claims")
+	)
+
+	if !exists {
+		return
+	}
+
+	roles = utils.ClaimStrings(pClaims.(jwt.MapClaims), "roles")
+	permissions = utils.ClaimStrings(pClaims.(jwt.MapClaims), "permissions")
+
+	return
+}
+
+// returns the claims of the token that are passed on to tokens issued from it
+func getCarriedClaims(claims jwt.MapClaims) jwt.MapClaims {
+
+	var (
+		carried = jwt.MapClaims{}
+	)
+
+	for key, value := range claims {
+		if !registeredClaims[key] {
+			carried[key] = value
+		}
+	}
+
+	return carried
+}
@@ -0,0 +1,394 @@
+package endpoints
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"PSF-LoginAPI/mail"
+	"PSF-LoginAPI/response"
+	"PSF-LoginAPI/utils"
+)
+
+// device IDs are opaque to the API, launchers may use a GUID or a hash of machine properties
+var deviceIDRegex = regexp.MustCompile(`^[!-~]{8,128}$`)
+
+type Device struct {
+	ID        int64      `db:"id"`
+	FirstSeen time.Time  `db:"first_seen"`
+	LastSeen  time.Time  `db:"last_seen"`
+	IP        *string    `db:"ip"`
+	Launcher  string     `db:"launcher"`
+	Platform  string     `db:"platform"`
+	TrustedAt *time.Time `db:"trusted_at"`
+}
+
+type DeviceLoginRequest struct {
+	Challenge string `json:"challenge" binding:"required"`
+	// code sent to the email of the account
+	Code string `json:"code" binding:"required"`
+}
+
+// only the hash of the device ID is stored
+func hashDeviceID(device string) []byte {
+
+	hash := sha256.Sum256([]byte(device))
+
+	return hash[:]
+}
+
+// returns true if the device of the account was confirmed before and new device verification is enabled,
+// logins without device ID are never trusted
+func isTrustedDevice(account int64, device string) (statusCode int, trusted bool) {
+
+	var (
+		err error
+	)
+
+	if device == "" || utils.GetDeviceVerificationMode() == utils.DeviceVerificationDisabled {
+		return
+	}
+
+	err = utils.GetPostgrePool().QueryRow(
+		context.Background(),
+		`SELECT "trusted_at" IS NOT NULL FROM "account_device" WHERE "account_id" = $1 AND "device_hash" = $2`,
+		account,
+		hashDeviceID(device),
+	).Scan(&trusted)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		statusCode = response.ResponseErrorDatabase
+
+		fmt.Printf("Error getting device of account %d from DB: %s\n", account, err.Error())
+
+		return
+	}
+
+	return
+}
+
+// accounts with a verified email confirm untrusted devices with an emailed code if enabled,
+// launchers that do not send a device ID confirm every login
+func requiresDeviceConfirmation(account *Account) bool {
+	return utils.GetDeviceVerificationMode() == utils.DeviceVerificationEmail && account.Email != nil && account.EmailVerified
+}
+
+// sends a confirmation code to the email of the account and responds with the challenge for LoginDevice
+func issueDeviceChallenge(gc *gin.Context, state *loginState, account *Account) {
+
+	var (
+		err error
+
+		statusCode int
+
+		code string
+	)
+
+	statusCode, code = createAccountCode(account.ID, AccountCodeDevice, nil, deviceCodeLifetime)
+	if statusCode == response.ResponseErrorSuccess {
+
+		err = sendAccountCode(gc.Request.Context(), account.Username, *account.Email, AccountCodeDevice, code)
+		if err != nil {
+			statusCode = response.ResponseErrorInternalMailFailed
+
+			fmt.Printf("Error sending device confirmation email to account %d: %s\n", account.ID, err.Error())
+		}
+	}
+	if statusCode != response.ResponseErrorSuccess {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(statusCode),
+		)
+
+		return
+	}
+
+	issueLoginChallenge(gc, state, utils.TokenPurposeDevice, response.ResponseErrorDeviceConfirmationRequired, deviceCodeLifetime)
+}
+
+// LoginDevice completes a login from an untrusted device with the challenge of Login and the code sent to the email
+func LoginDevice(gc *gin.Context) {
+
+	var (
+		err error
+
+		ok bool
+
+		statusCode int
+
+		state *loginState
+
+		loginRequest DeviceLoginRequest
+	)
+
+	err = gc.BindJSON(&loginRequest)
+	if err != nil {
+		fmt.Println("Could not parse request body as POST LoginDevice")
+
+		return
+	}
+
+	state, ok = parseLoginChallenge(gc, loginRequest.Challenge, utils.TokenPurposeDevice)
+	if !ok {
+		return
+	}
+
+	statusCode, _ = useAccountCode(state.Account, AccountCodeDevice, loginRequest.Code)
+	if statusCode != response.ResponseErrorSuccess {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(statusCode),
+		)
+
+		return
+	}
+
+	completeChallengeLogin(gc, state, loginRequest.Challenge, deviceCodeLifetime)
+}
+
+// records the device of the login, the account is notified by email about devices it did not use before.
+// Failing to record the device does not fail the login.
+func recordDevice(gc *gin.Context, state *loginState) {
+
+	var (
+		err error
+
+		id    int64
+		added bool
+	)
+
+	// logins without device ID can not be told apart
+	if state.Device == "" {
+		return
+	}
+
+	err = utils.GetPostgrePool().QueryRow(
+		context.Background(),
+		`
+INSERT INTO "account_device" ("account_id", "device_hash", "ip", "launcher", "platform", "trusted_at")
+VALUES ($1, $2, $3, $4, $5, CASE WHEN $6::BOOLEAN THEN NOW() END)
+ON CONFLICT ("account_id", "device_hash") DO UPDATE
+SET
+	"last_seen" = NOW(),
+	"ip" = EXCLUDED."ip",
+	"launcher" = EXCLUDED."launcher",
+	"platform" = EXCLUDED."platform",
+	"trusted_at" = COALESCE("account_device"."trusted_at", EXCLUDED."trusted_at")
+RETURNING "id", "xmax" = 0
+`,
+		state.Account,
+		hashDeviceID(state.Device),
+		gc.ClientIP(),
+		state.Launcher,
+		state.Platform,
+		state.Confirmed,
+	).Scan(&id, &added)
+	if err != nil {
+
+		fmt.Printf("Error recording device of account %d: %s\n", state.Account, err.Error())
+
+		return
+	}
+
+	if !added {
+		return
+	}
+
+	writeAuditEvent(
+		gc,
+		state.Account,
+		AuditEventDeviceAdded,
+		map[string]interface{}{
+			"device":   id,
+			"launcher": state.Launcher,
+			"platform": state.Platform,
+		},
+	)
+
+	if _, mailEnabled := utils.GetMailer(); mailEnabled {
+		go notifyNewDevice(state.Account, gc.ClientIP(), state.Platform)
+	}
+}
+
+// tells the account by email that it logged in from a new device, if it has a verified email
+func notifyNewDevice(accountID int64, ip string, platform string) {
+
+	var (
+		err error
+
+		statusCode int
+
+		account *Account
+
+		mailer, _ = utils.GetMailer()
+	)
+
+	statusCode, account = loadAccount(`"id" = $1`, accountID)
+	if statusCode != response.ResponseErrorSuccess || account == nil || account.Email == nil || !account.EmailVerified {
+		return
+	}
+
+	err = mailer.Send(
+		context.Background(),
+		&mail.Message{
+			To:      *account.Email,
+			Subject: "New login to your PSForever account",
+			Body: fmt.Sprintf(
+				"Hello %s,\n\nyour account logged in from a new device (%s) from %s.\n\nIf this was not you, change your password and revoke the device in the launcher.\n",
+				account.Username,
+				platform,
+				ip,
+			),
+		},
+	)
+	if err != nil {
+		fmt.Printf("Error sending new device email to account %d: %s\n", account.ID, err.Error())
+	}
+}
+
+// GetDevices lists the devices the token account logged in from
+func GetDevices(gc *gin.Context) {
+
+	var (
+		err error
+
+		ok bool
+
+		account int64
+
+		rows    pgx.Rows
+		devices []Device
+
+		devicesResponse = response.DevicesResponse{
+			DefaultResponse: response.DefaultResponse{
+				Status: response.ResponseErrorSuccess,
+			},
+			Devices: []response.Device{},
+		}
+	)
+
+	account, ok = getClaimsAccount(gc)
+	if !ok {
+		gc.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	rows, err = utils.GetPostgrePool().Query(
+		context.Background(),
+		`
+SELECT "id", "first_seen", "last_seen", HOST("ip") AS "ip", "launcher", "platform", "trusted_at"
+FROM "account_device"
+WHERE "account_id" = $1
+ORDER BY "last_seen" DESC
+`,
+		account,
+	)
+	if err == nil {
+		devices, err = pgx.CollectRows(rows, pgx.RowToStructByName[Device])
+	}
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+
+		fmt.Printf("Error getting devices of account %d from DB: %s\n", account, err.Error())
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(response.ResponseErrorDatabase),
+		)
+
+		return
+	}
+
+	for _, device := range devices {
+		devicesResponse.Devices = append(
+			devicesResponse.Devices,
+			response.Device{
+				ID:        device.ID,
+				FirstSeen: device.FirstSeen.Unix(),
+				LastSeen:  device.LastSeen.Unix(),
+				IP:        device.IP,
+				Launcher:  device.Launcher,
+				Platform:  device.Platform,
+				Trusted:   device.TrustedAt != nil,
+			},
+		)
+	}
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		devicesResponse,
+	)
+}
+
+// RevokeDevice forgets a device of the token account, its next login counts as a new device
+func RevokeDevice(gc *gin.Context) {
+
+	var (
+		err error
+
+		ok bool
+
+		account int64
+		id      int64
+
+		tag pgconn.CommandTag
+	)
+
+	account, ok = getClaimsAccount(gc)
+	if !ok {
+		gc.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	id, ok = getIDParam(gc, "id")
+	if !ok {
+		return
+	}
+
+	tag, err = utils.GetPostgrePool().Exec(
+		context.Background(),
+		`DELETE FROM "account_device" WHERE "id" = $1 AND "account_id" = $2`,
+		id,
+		account,
+	)
+	if err != nil {
+
+		fmt.Printf("Error revoking device %d of account %d: %s\n", id, account, err.Error())
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(response.ResponseErrorDatabase),
+		)
+
+		return
+	}
+
+	if tag.RowsAffected() == 0 {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(response.ResponseErrorUnknownDevice),
+		)
+
+		return
+	}
+
+	fmt.Printf("Account ID [%d] revoked device %d\n", account, id)
+
+	writeAuditEvent(gc, account, AuditEventDeviceRevoked, map[string]interface{}{"device": id})
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		response.DefaultResponse{
+			Status: response.ResponseErrorSuccess,
+		},
+	)
+}
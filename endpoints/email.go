@@ -0,0 +1,585 @@
+package endpoints
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	netmail "net/mail"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"PSF-LoginAPI/mail"
+	"PSF-LoginAPI/password"
+	"PSF-LoginAPI/response"
+	"PSF-LoginAPI/utils"
+)
+
+// Account code purposes
+const (
+	AccountCodeEmailVerification = "email_verification"
+	AccountCodePasswordReset     = "password_reset"
+	AccountCodeDevice            = "device"
+)
+
+const accountCodeLength = 10
+
+const (
+	emailVerificationLifetime = 24 * time.Hour
+	passwordResetLifetime     = 30 * time.Minute
+	deviceCodeLifetime        = 15 * time.Minute
+)
+
+const maxEmailLength = 254
+
+type SetEmailRequest struct {
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type VerifyEmailRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type PasswordResetRequest struct {
+	// username or verified email
+	Account string `json:"account" binding:"required"`
+}
+
+type PasswordResetConfirmRequest struct {
+	// username or verified email
+	Account     string `json:"account" binding:"required"`
+	Code        string `json:"code" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required"`
+}
+
+// returns false if the email is not a plain address
+func isValidEmail(email string) bool {
+
+	address, err := netmail.ParseAddress(email)
+
+	return err == nil && address.Name == "" && address.Address == email && len(email) <= maxEmailLength
+}
+
+func hashAccountCode(code string) []byte {
+
+	hash := sha256.Sum256([]byte(normalizeCode(code)))
+
+	return hash[:]
+}
+
+// creates a one-time code for the account, unused codes of the purpose are dropped
+func createAccountCode(account int64, purpose string, email *string, lifetime time.Duration) (statusCode int, code string) {
+
+	var (
+		err error
+	)
+
+	code, err = utils.RandomCode(accountCodeLength)
+	if err == nil {
+		err = pgx.BeginFunc(
+			context.Background(),
+			utils.GetPostgrePool(),
+			func(tx pgx.Tx) error {
+
+				_, err := tx.Exec(
+					context.Background(),
+					`DELETE FROM "account_code" WHERE "account_id" = $1 AND "purpose" = $2 AND "used_at" IS NULL`,
+					account,
+					purpose,
+				)
+				if err != nil {
+					return err
+				}
+
+				_, err = tx.Exec(
+					context.Background(),
+					`
+INSERT INTO "account_code" ("account_id", "purpose", "code_hash", "email", "expires_at")
+VALUES ($1, $2, $3, $4, NOW() + $5::BIGINT * INTERVAL '1 second')
+`,
+					account,
+					purpose,
+					hashAccountCode(code),
+					email,
+					int64(lifetime.Seconds()),
+				)
+
+				return err
+			},
+		)
+	}
+	if err != nil {
+		statusCode = response.ResponseErrorDatabase
+
+		fmt.Printf("Error creating %s code for account %d: %s\n", purpose, account, err.Error())
+
+		return
+	}
+
+	return
+}
+
+// marks the code of the account as used, returns ResponseErrorInvalidCode if it is unknown, used or expired,
+// the email is the one a verification code was sent to
+func useAccountCode(account int64, purpose string, code string) (statusCode int, email *string) {
+
+	var (
+		err error
+	)
+
+	err = utils.GetPostgrePool().QueryRow(
+		context.Background(),
+		`
+UPDATE "account_code"
+SET "used_at" = NOW()
+WHERE "account_id" = $1 AND "purpose" = $2 AND "code_hash" = $3 AND "used_at" IS NULL AND "expires_at" > NOW()
+RETURNING "email"
+`,
+		account,
+		purpose,
+		hashAccountCode(code),
+	).Scan(&email)
+	if errors.Is(err, pgx.ErrNoRows) {
+		statusCode = response.ResponseErrorInvalidCode
+
+		fmt.Printf("Account ID [%d] used invalid %s code\n", account, purpose)
+
+		return
+	}
+	if err != nil {
+		statusCode = response.ResponseErrorDatabase
+
+		fmt.Printf("Error using %s code of account %d: %s\n", purpose, account, err.Error())
+
+		return
+	}
+
+	return
+}
+
+// sends the code to the email
+func sendAccountCode(ctx context.Context, username string, email string, purpose string, code string) error {
+
+	var (
+		message = &mail.Message{
+			To: email,
+		}
+
+		mailer, _ = utils.GetMailer()
+	)
+
+	switch purpose {
+	case AccountCodeEmailVerification:
+		message.Subject = "Verify your PSForever email address"
+		message.Body = fmt.Sprintf(
+			"Hello %s,\n\nyour verification code is %s\n\nIt expires in 24 hours. If you did not add this address to your account, you can ignore this email.\n",
+			username,
+			code,
+		)
+
+	case AccountCodePasswordReset:
+		message.Subject = "Reset your PSForever password"
+		message.Body = fmt.Sprintf(
+			"Hello %s,\n\nyour password reset code is %s\n\nEnter it in the launcher to set a new password, it expires in 30 minutes. If you did not request a password reset, you can ignore this email.\n",
+			username,
+			code,
+		)
+
+	case AccountCodeDevice:
+		message.Subject = "Confirm your PSForever login"
+		message.Body = fmt.Sprintf(
+			"Hello %s,\n\nyour account is logging in from a new device, the code to confirm it is %s\n\nIt expires in 15 minutes. If this was not you, change your password.\n",
+			username,
+			code,
+		)
+	}
+
+	return mailer.Send(ctx, message)
+}
+
+// creates a verification code for the email of the account and sends it
+func sendEmailVerification(ctx context.Context, account int64, username string, email string) (statusCode int) {
+
+	var (
+		err error
+
+		code string
+	)
+
+	statusCode, code = createAccountCode(account, AccountCodeEmailVerification, &email, emailVerificationLifetime)
+	if statusCode != response.ResponseErrorSuccess {
+		return
+	}
+
+	err = sendAccountCode(ctx, username, email, AccountCodeEmailVerification, code)
+	if err != nil {
+		statusCode = response.ResponseErrorInternalMailFailed
+
+		fmt.Printf("Error sending verification email to account %d: %s\n", account, err.Error())
+
+		return
+	}
+
+	return
+}
+
+// SetEmail replaces the email of the token account and sends a verification code to it
+func SetEmail(gc *gin.Context) {
+
+	var (
+		err error
+
+		ok bool
+
+		statusCode int
+
+		accountID int64
+
+		account *Account
+
+		emailRequest SetEmailRequest
+	)
+
+	err = gc.BindJSON(&emailRequest)
+	if err != nil {
+		fmt.Println("Could not parse request body as PUT SetEmail")
+
+		return
+	}
+
+	accountID, ok = getClaimsAccount(gc)
+	if !ok {
+		gc.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	if !isValidEmail(emailRequest.Email) {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(response.ResponseErrorInvalidEmail),
+		)
+
+		return
+	}
+
+	// the email controls password resets
+	statusCode, account = loadAccount(`"id" = $1`, accountID)
+	if statusCode == response.ResponseErrorSuccess {
+		statusCode = checkAccountPassword(gc.Request.Context(), account, emailRequest.Password)
+	}
+	if statusCode == response.ResponseErrorSuccess {
+		statusCode = setAccountEmail(account.ID, emailRequest.Email)
+	}
+	if statusCode == response.ResponseErrorSuccess {
+		statusCode = sendEmailVerification(gc.Request.Context(), account.ID, account.Username, emailRequest.Email)
+	}
+	if statusCode != response.ResponseErrorSuccess {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(statusCode),
+		)
+
+		return
+	}
+
+	fmt.Printf("User [%s] with ID %d changed email\n", account.Username, account.ID)
+
+	writeAuditEvent(gc, account.ID, AuditEventEmailChanged, nil)
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		response.DefaultResponse{
+			Status: response.ResponseErrorSuccess,
+		},
+	)
+}
+
+// sets the unverified email of the account
+func setAccountEmail(account int64, email string) (statusCode int) {
+
+	var (
+		err error
+
+		pgError *pgconn.PgError
+	)
+
+	_, err = utils.GetPostgrePool().Exec(
+		context.Background(),
+		`UPDATE "account" SET "email" = $2, "email_verified_at" = NULL WHERE "id" = $1`,
+		account,
+		email,
+	)
+
+	switch {
+	// the unique index on the lowercase email
+	case errors.As(err, &pgError) && pgError.Code == "23505":
+		statusCode = response.ResponseErrorEmailTaken
+
+	case err != nil:
+		statusCode = response.ResponseErrorDatabase
+
+		fmt.Printf("Error setting email of account %d: %s\n", account, err.Error())
+	}
+
+	return
+}
+
+// VerifyEmail marks the email of the token account as verified with the code sent to it
+func VerifyEmail(gc *gin.Context) {
+
+	var (
+		err error
+
+		ok bool
+
+		statusCode int
+
+		account int64
+
+		email *string
+
+		verifyRequest VerifyEmailRequest
+	)
+
+	err = gc.BindJSON(&verifyRequest)
+	if err != nil {
+		fmt.Println("Could not parse request body as POST VerifyEmail")
+
+		return
+	}
+
+	account, ok = getClaimsAccount(gc)
+	if !ok {
+		gc.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	statusCode, email = useAccountCode(account, AccountCodeEmailVerification, verifyRequest.Code)
+	if statusCode == response.ResponseErrorSuccess {
+
+		var tag pgconn.CommandTag
+
+		// the email might have changed since the code was sent
+		tag, err = utils.GetPostgrePool().Exec(
+			context.Background(),
+			`UPDATE "account" SET "email_verified_at" = NOW() WHERE "id" = $1 AND "email" = $2`,
+			account,
+			email,
+		)
+
+		switch {
+		case err != nil:
+			statusCode = response.ResponseErrorDatabase
+
+			fmt.Printf("Error verifying email of account %d: %s\n", account, err.Error())
+
+		case tag.RowsAffected() == 0:
+			statusCode = response.ResponseErrorInvalidCode
+		}
+	}
+	if statusCode != response.ResponseErrorSuccess {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(statusCode),
+		)
+
+		return
+	}
+
+	fmt.Printf("Account ID [%d] verified email\n", account)
+
+	writeAuditEvent(gc, account, AuditEventEmailVerified, nil)
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		response.DefaultResponse{
+			Status: response.ResponseErrorSuccess,
+		},
+	)
+}
+
+// returns the account with the verified email if the input contains an @, otherwise the one with the username,
+// nil if there is none. Usernames are registered without @, so the input never matches two accounts.
+func loadAccountForReset(usernameOrEmail string) (statusCode int, account *Account) {
+
+	if strings.Contains(usernameOrEmail, "@") {
+		return loadAccount(
+			`LOWER("email") = LOWER($1) AND "email_verified_at" IS NOT NULL`,
+			usernameOrEmail,
+		)
+	}
+
+	return loadAccount(
+		`LOWER("username") = LOWER($1)`,
+		usernameOrEmail,
+	)
+}
+
+// RequestPasswordReset sends a password reset code to the verified email of the account.
+// The response is the same whether the account exists and has a verified email or not.
+func RequestPasswordReset(gc *gin.Context) {
+
+	var (
+		err error
+
+		resetRequest PasswordResetRequest
+
+		// the request context ends with the response
+		auditContext = gc.Copy()
+	)
+
+	err = gc.BindJSON(&resetRequest)
+	if err != nil {
+		fmt.Println("Could not parse request body as POST RequestPasswordReset")
+
+		return
+	}
+
+	// do not let the time it takes tell if an email was sent
+	go func() {
+
+		var (
+			err error
+
+			statusCode int
+
+			code string
+
+			account *Account
+		)
+
+		statusCode, account = loadAccountForReset(resetRequest.Account)
+		if statusCode != response.ResponseErrorSuccess {
+			return
+		}
+
+		if account == nil || account.Email == nil || !account.EmailVerified {
+
+			fmt.Printf("Password reset requested for account without verified email: %s\n", resetRequest.Account)
+
+			return
+		}
+
+		statusCode, code = createAccountCode(account.ID, AccountCodePasswordReset, nil, passwordResetLifetime)
+		if statusCode != response.ResponseErrorSuccess {
+			return
+		}
+
+		err = sendAccountCode(context.Background(), account.Username, *account.Email, AccountCodePasswordReset, code)
+		if err != nil {
+
+			fmt.Printf("Error sending password reset email to account %d: %s\n", account.ID, err.Error())
+
+			return
+		}
+
+		fmt.Printf("User [%s] with ID %d requested password reset\n", account.Username, account.ID)
+
+		writeAuditEvent(auditContext, account.ID, AuditEventPasswordResetRequested, nil)
+	}()
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		response.DefaultResponse{
+			Status: response.ResponseErrorSuccess,
+		},
+	)
+}
+
+// ConfirmPasswordReset sets a new password with the code sent by RequestPasswordReset,
+// all sessions and the game token of the account are revoked
+func ConfirmPasswordReset(gc *gin.Context) {
+
+	var (
+		err error
+
+		statusCode int
+
+		hash string
+
+		account *Account
+
+		confirmRequest PasswordResetConfirmRequest
+	)
+
+	err = gc.BindJSON(&confirmRequest)
+	if err != nil {
+		fmt.Println("Could not parse request body as POST ConfirmPasswordReset")
+
+		return
+	}
+
+	// unknown accounts take the same path as known ones with a wrong code, the policy is checked
+	// against the input instead of the username of the account so its result does not tell if the account exists
+	if !checkPasswordPolicy(gc, confirmRequest.NewPassword, confirmRequest.Account) {
+		return
+	}
+
+	hash, err = utils.GetPasswordPool().Hash(gc.Request.Context(), utils.GetPasswordHasher(), confirmRequest.NewPassword)
+	if err != nil {
+
+		statusCode = response.ResponseErrorInternalServerBusy
+		if !errors.Is(err, password.ErrOverloaded) {
+			statusCode = response.ResponseErrorInternalPasswordHashFailed
+
+			fmt.Printf("Error hashing new password for reset of %s: %s\n", confirmRequest.Account, err.Error())
+		}
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(statusCode),
+		)
+
+		return
+	}
+
+	statusCode, account = loadAccountForReset(confirmRequest.Account)
+	if statusCode != response.ResponseErrorSuccess {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(statusCode),
+		)
+
+		return
+	}
+
+	if account == nil {
+		// no account has the ID 0, the code is rejected as invalid like a wrong one
+		account = &Account{}
+	}
+
+	statusCode, _ = useAccountCode(account.ID, AccountCodePasswordReset, confirmRequest.Code)
+	if statusCode == response.ResponseErrorSuccess {
+		statusCode, _ = setAccountPassword(account.ID, hash)
+	}
+	if statusCode != response.ResponseErrorSuccess {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(statusCode),
+		)
+
+		return
+	}
+
+	fmt.Printf("User [%s] with ID %d reset password\n", account.Username, account.ID)
+
+	writeAuditEvent(gc, account.ID, AuditEventPasswordReset, nil)
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		response.DefaultResponse{
+			Status: response.ResponseErrorSuccess,
+		},
+	)
+}
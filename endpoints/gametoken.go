@@ -5,29 +5,146 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 
 	"PSF-LoginAPI/response"
+	"PSF-LoginAPI/signature"
 	"PSF-LoginAPI/utils"
 )
 
 func GameToken(gc *gin.Context) {
 
+	var (
+		ok bool
+
+		gameToken string
+	)
+
+	ok, gameToken, _, _ = issueGameToken(gc)
+	if !ok {
+		return
+	}
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		response.GameTokenResponse{
+			DefaultResponse: response.DefaultResponse{
+				Status: response.ResponseErrorSuccess,
+			},
+			GameToken: gameToken,
+		},
+	)
+
+	return
+}
+
+// GameTokenV2 returns the game token with the signed connection details of the mode
+func GameTokenV2(gc *gin.Context) {
+
+	var (
+		err error
+
+		ok bool
+
+		statusCode int
+
+		gameToken      string
+		connectionSign string
+
+		account int64
+		modeID  int64
+
+		mode *Mode
+
+		connection signature.Connection
+
+		privateKey, signingEnabled = utils.GetResponseSigningKey()
+	)
+
+	modeID, ok = getClaimsMode(gc)
+	if !ok {
+		gc.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	// issuing replaces the stored game token, the mode has to be valid before
+	statusCode, mode = getMode(modeID)
+	if statusCode == response.ResponseErrorSuccess && mode == nil {
+		statusCode = response.ResponseErrorUnknownMode
+	}
+	if statusCode != response.ResponseErrorSuccess {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(statusCode),
+		)
+
+		return
+	}
+
+	ok, gameToken, account, _ = issueGameToken(gc)
+	if !ok {
+		return
+	}
+
+	connection = signature.Connection{
+		GameToken:  gameToken,
+		Mode:       mode.ID,
+		WorldHost:  mode.WorldHost,
+		WorldPort:  mode.WorldPort,
+		LaunchArgs: mode.LaunchArgs,
+		IssuedAt:   time.Now().Unix(),
+	}
+
+	if signingEnabled {
+		connectionSign, err = signature.SignConnection(privateKey, &connection)
+		if err != nil {
+
+			fmt.Printf("Connection signing for account ID [%d] failed: %s\n", account, err.Error())
+
+			gc.IndentedJSON(
+				http.StatusOK,
+				response.CreateErrorResponse(response.ResponseErrorInternalTokenCreationFailed),
+			)
+
+			return
+		}
+	}
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		response.GameTokenResponseV2{
+			DefaultResponse: response.DefaultResponse{
+				Status: response.ResponseErrorSuccess,
+			},
+			Connection: connection,
+			Signature:  connectionSign,
+		},
+	)
+}
+
+// creates a game token for the verified account and stores it,
+// returns false if the error response was already written
+func issueGameToken(gc *gin.Context) (ok bool, gameToken string, account int64, mode int64) {
+
 	var (
 		exists bool
 
-		// token can only be a maximum of 31 characters (31 + \0)
-		gameToken = utils.RandString(31)
+		statusCode int
 
 		pClaims, _ = gc.Get("claims")
 		claims     = pClaims.(jwt.MapClaims)
-
-		account, _ = claims["account"].(json.Number).Int64()
-		mode, _    = claims["mode"].(json.Number).Int64()
 	)
 
+	// token can only be a maximum of 31 characters (31 + \0)
+	gameToken = utils.RandString(31)
+
+	account, _ = claims["account"].(json.Number).Int64()
+	mode, _ = claims["mode"].(json.Number).Int64()
+
 	_, exists = claims["verified"]
 	if !exists {
 
@@ -45,19 +162,27 @@ func GameToken(gc *gin.Context) {
 		return
 	}
 
-	setTokenOnAccount(account, gameToken)
+	// tokens issued before a ban are still valid and can be refreshed
+	if !checkBan(gc, account) {
+		return
+	}
 
-	gc.IndentedJSON(
-		http.StatusOK,
-		response.GameTokenResponse{
-			DefaultResponse: response.DefaultResponse{
-				Status: response.ResponseErrorSuccess,
-			},
-			GameToken: gameToken,
-		},
-	)
+	if !checkMaintenance(gc, account, utils.ClaimStrings(claims, "permissions"), mode) {
+		return
+	}
 
-	return
+	statusCode = setTokenOnAccount(account, gameToken)
+	if statusCode != response.ResponseErrorSuccess {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(statusCode),
+		)
+
+		return
+	}
+
+	return true, gameToken, account, mode
 }
 
 func setTokenOnAccount(account int64, gameToken string) (statusCode int) {
@@ -66,7 +191,7 @@ func setTokenOnAccount(account int64, gameToken string) (statusCode int) {
 		err error
 	)
 
-	_, err = utils.GetPostgrePool().Query(
+	_, err = utils.GetPostgrePool().Exec(
 		context.Background(),
 		`UPDATE "account" SET "token" = $1 WHERE "id" = $2`,
 		gameToken,
@@ -0,0 +1,542 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5"
+
+	"PSF-LoginAPI/response"
+	"PSF-LoginAPI/utils"
+)
+
+// Invite targets
+const (
+	// code is required to register if registration needs an invite
+	InviteTargetRegistration = "registration"
+	// code grants access to a mode regardless of its required role and permission
+	InviteTargetMode = "mode"
+)
+
+const inviteCodeLength = 16
+
+// most codes created with one request
+const maxInviteBatchSize = 1000
+
+const inviteCodeQuery = `
+SELECT
+	"code",
+	"batch",
+	"target",
+	"mode",
+	"max_uses",
+	"uses",
+	"created_at",
+	"expires_at",
+	"revoked_at",
+	"created_by",
+	COALESCE(
+		(
+			SELECT array_agg(redemption."account_id" ORDER BY redemption."redeemed_at")
+			FROM "invite_redemption" AS redemption
+			WHERE redemption."code" = invite_code."code"
+		),
+		'{}'::INTEGER[]
+	)::BIGINT[] AS "accounts"
+FROM "invite_code"
+WHERE %s
+ORDER BY "created_at" DESC, "code"
+`
+
+// invite codes that can be redeemed right now
+const activeInviteCodeCondition = `"uses" < "max_uses" AND "revoked_at" IS NULL AND ("expires_at" IS NULL OR "expires_at" > NOW())`
+
+var (
+	errInvalidInviteCode  = errors.New("invite code invalid or used up")
+	errInviteCodeRedeemed = errors.New("invite code already redeemed by the account")
+)
+
+type InviteCode struct {
+	Code   string `db:"code"`
+	Batch  string `db:"batch"`
+	Target string `db:"target"`
+	// nil for registration codes
+	Mode      *int64     `db:"mode"`
+	MaxUses   int32      `db:"max_uses"`
+	Uses      int32      `db:"uses"`
+	CreatedAt time.Time  `db:"created_at"`
+	ExpiresAt *time.Time `db:"expires_at"`
+	RevokedAt *time.Time `db:"revoked_at"`
+	CreatedBy *int64     `db:"created_by"`
+	// accounts that redeemed the code, oldest first
+	Accounts []int64 `db:"accounts"`
+}
+
+type RedeemInviteRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type AdminInviteCodesRequest struct {
+	Count  int    `json:"count" binding:"required"`
+	Target string `json:"target" binding:"required"`
+	// required for mode codes
+	Mode *int64 `json:"mode"`
+	// 1 if not set
+	MaxUses int32 `json:"maxUses"`
+	// unix time, codes do not expire if not set
+	ExpiresAt int64 `json:"expiresAt"`
+	// generated if not set
+	Batch string `json:"batch"`
+}
+
+func (invite *InviteCode) isActive(now time.Time) bool {
+	return invite.Uses < invite.MaxUses && invite.RevokedAt == nil && (invite.ExpiresAt == nil || now.Before(*invite.ExpiresAt))
+}
+
+func createInviteCode(invite *InviteCode) response.InviteCode {
+
+	var (
+		expiresAt int64
+		revokedAt int64
+	)
+
+	if invite.ExpiresAt != nil {
+		expiresAt = invite.ExpiresAt.Unix()
+	}
+
+	if invite.RevokedAt != nil {
+		revokedAt = invite.RevokedAt.Unix()
+	}
+
+	return response.InviteCode{
+		Code:      invite.Code,
+		Batch:     invite.Batch,
+		Target:    invite.Target,
+		Mode:      invite.Mode,
+		MaxUses:   invite.MaxUses,
+		Uses:      invite.Uses,
+		CreatedAt: invite.CreatedAt.Unix(),
+		ExpiresAt: expiresAt,
+		RevokedAt: revokedAt,
+		CreatedBy: invite.CreatedBy,
+		Accounts:  emptyIfNil(invite.Accounts),
+		Active:    invite.isActive(time.Now()),
+	}
+}
+
+// codes are shown in uppercase, players may type them in any case
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// uses the code for the account within the transaction,
+// mode codes grant the account access to their mode
+func redeemInviteCode(tx pgx.Tx, code string, target string, account int64) (mode *int64, err error) {
+
+	err = tx.QueryRow(
+		context.Background(),
+		`UPDATE "invite_code" SET "uses" = "uses" + 1 WHERE "code" = $1 AND "target" = $2 AND `+activeInviteCodeCondition+` RETURNING "mode"`,
+		normalizeCode(code),
+		target,
+	).Scan(&mode)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errInvalidInviteCode
+	}
+	if err != nil {
+		return
+	}
+
+	tag, err := tx.Exec(
+		context.Background(),
+		`INSERT INTO "invite_redemption" ("code", "account_id") VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+		normalizeCode(code),
+		account,
+	)
+	if err != nil {
+		return
+	}
+
+	if tag.RowsAffected() == 0 {
+		return nil, errInviteCodeRedeemed
+	}
+
+	if mode != nil {
+		_, err = tx.Exec(
+			context.Background(),
+			`INSERT INTO "account_mode_grant" ("account_id", "mode") VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+			account,
+			*mode,
+		)
+	}
+
+	return
+}
+
+// returns the status code for an error of redeemInviteCode
+func getInviteStatusCode(err error) int {
+
+	switch {
+	case err == nil:
+		return response.ResponseErrorSuccess
+	case errors.Is(err, errInvalidInviteCode):
+		return response.ResponseErrorInvalidInviteCode
+	case errors.Is(err, errInviteCodeRedeemed):
+		return response.ResponseErrorInviteCodeRedeemed
+	}
+
+	return response.ResponseErrorDatabase
+}
+
+// RedeemInvite grants the token account access to the mode of an invite code,
+// the response contains a new token with the access
+func RedeemInvite(gc *gin.Context) {
+
+	var (
+		err error
+
+		ok bool
+
+		statusCode int
+
+		token string
+
+		account int64
+		mode    *int64
+
+		roles       []string
+		permissions []string
+
+		redeemRequest RedeemInviteRequest
+
+		claims = gc.MustGet("claims").(jwt.MapClaims)
+	)
+
+	err = gc.BindJSON(&redeemRequest)
+	if err != nil {
+		fmt.Println("Could not parse request body as POST RedeemInvite")
+
+		return
+	}
+
+	account, ok = getClaimsAccount(gc)
+	if !ok {
+		gc.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	err = pgx.BeginFunc(
+		context.Background(),
+		utils.GetPostgrePool(),
+		func(tx pgx.Tx) (err error) {
+			mode, err = redeemInviteCode(tx, redeemRequest.Code, InviteTargetMode, account)
+			return
+		},
+	)
+
+	statusCode = getInviteStatusCode(err)
+	if statusCode == response.ResponseErrorDatabase {
+		fmt.Printf("Error redeeming invite code for account %d: %s\n", account, err.Error())
+	}
+
+	// the token has to carry the access to the mode
+	if statusCode == response.ResponseErrorSuccess {
+		statusCode, roles, permissions = getAccountRoles(account)
+	}
+	if statusCode != response.ResponseErrorSuccess {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(statusCode),
+		)
+
+		return
+	}
+
+	fmt.Printf("Account ID [%d] redeemed invite code for mode %d\n", account, *mode)
+
+	writeAuditEvent(
+		gc,
+		account,
+		AuditEventInviteRedeemed,
+		map[string]interface{}{
+			"code": normalizeCode(redeemRequest.Code),
+			"mode": *mode,
+		},
+	)
+
+	claims = getCarriedClaims(claims)
+	claims["roles"] = roles
+	claims["permissions"] = permissions
+
+	token, err = utils.GenerateToken(&claims)
+	if err != nil {
+
+		fmt.Printf("Token singing failed: %s\n", err.Error())
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(response.ResponseErrorInternalTokenCreationFailed),
+		)
+
+		return
+	}
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		response.TokenResponse{
+			DefaultResponse: response.DefaultResponse{
+				Status: response.ResponseErrorSuccess,
+			},
+			Token: token,
+		},
+	)
+}
+
+func loadInviteCodes(condition string, args ...interface{}) (statusCode int, invites []InviteCode) {
+
+	var (
+		err error
+
+		rows pgx.Rows
+	)
+
+	rows, err = utils.GetPostgrePool().Query(
+		context.Background(),
+		fmt.Sprintf(inviteCodeQuery, condition),
+		args...,
+	)
+	if err != nil {
+		statusCode = response.ResponseErrorDatabase
+
+		fmt.Printf("Error querying invite codes from DB: %s\n", err.Error())
+
+		return
+	}
+
+	invites, err = pgx.CollectRows(rows, pgx.RowToStructByName[InviteCode])
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		statusCode = response.ResponseErrorDatabase
+
+		fmt.Printf("Error parsing invite codes from DB: %s\n", err.Error())
+
+		return
+	}
+
+	return
+}
+
+// AdminGetInviteCodes lists invite codes newest first, only active ones if the active query parameter is true
+// and only the ones of a batch if the batch query parameter is set
+func AdminGetInviteCodes(gc *gin.Context) {
+
+	var (
+		condition = "TRUE"
+		args      []interface{}
+	)
+
+	if gc.Query("active") == "true" {
+		condition = activeInviteCodeCondition
+	}
+
+	if gc.Query("batch") != "" {
+		condition += ` AND "batch" = $1`
+		args = append(args, gc.Query("batch"))
+	}
+
+	respondInviteCodes(gc, condition, args...)
+}
+
+// AdminCreateInviteCodes creates a batch of invite codes and returns them
+func AdminCreateInviteCodes(gc *gin.Context) {
+
+	var (
+		err error
+
+		statusCode int
+
+		mode *Mode
+
+		codes []string
+
+		invitesRequest AdminInviteCodesRequest
+
+		batch = &pgx.Batch{}
+		admin = getAdminAccount(gc)
+	)
+
+	err = gc.BindJSON(&invitesRequest)
+	if err != nil {
+		fmt.Println("Could not parse request body as POST AdminCreateInviteCodes")
+
+		return
+	}
+
+	if invitesRequest.MaxUses == 0 {
+		invitesRequest.MaxUses = 1
+	}
+
+	if invitesRequest.Batch == "" {
+		invitesRequest.Batch = time.Now().UTC().Format("20060102-150405")
+	}
+
+	switch {
+	case invitesRequest.Count < 1 || invitesRequest.Count > maxInviteBatchSize,
+		invitesRequest.MaxUses < 1,
+		len(invitesRequest.Batch) > 64,
+		invitesRequest.ExpiresAt != 0 && invitesRequest.ExpiresAt <= time.Now().Unix(),
+		invitesRequest.Target == InviteTargetRegistration && invitesRequest.Mode != nil,
+		invitesRequest.Target == InviteTargetMode && invitesRequest.Mode == nil,
+		invitesRequest.Target != InviteTargetRegistration && invitesRequest.Target != InviteTargetMode:
+
+		gc.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	if invitesRequest.Mode != nil {
+
+		statusCode, mode = getMode(*invitesRequest.Mode)
+		if statusCode == response.ResponseErrorSuccess && mode == nil {
+			statusCode = response.ResponseErrorUnknownMode
+		}
+		if statusCode != response.ResponseErrorSuccess {
+
+			gc.IndentedJSON(
+				http.StatusOK,
+				response.CreateErrorResponse(statusCode),
+			)
+
+			return
+		}
+	}
+
+	for i := 0; i < invitesRequest.Count; i++ {
+
+		code, err := utils.RandomCode(inviteCodeLength)
+		if err != nil {
+
+			fmt.Printf("Error creating invite code: %s\n", err.Error())
+
+			gc.IndentedJSON(
+				http.StatusOK,
+				response.CreateErrorResponse(response.ResponseErrorInternalTokenCreationFailed),
+			)
+
+			return
+		}
+
+		codes = append(codes, code)
+
+		batch.Queue(
+			`
+INSERT INTO "invite_code" ("code", "batch", "target", "mode", "max_uses", "expires_at", "created_by")
+VALUES ($1, $2, $3, $4, $5, to_timestamp(NULLIF($6::BIGINT, 0)), NULLIF($7::INTEGER, 0))
+`,
+			code,
+			invitesRequest.Batch,
+			invitesRequest.Target,
+			invitesRequest.Mode,
+			invitesRequest.MaxUses,
+			invitesRequest.ExpiresAt,
+			admin,
+		)
+	}
+
+	err = utils.GetPostgrePool().SendBatch(context.Background(), batch).Close()
+	if err != nil {
+
+		fmt.Printf("Error creating invite codes: %s\n", err.Error())
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(response.ResponseErrorDatabase),
+		)
+
+		return
+	}
+
+	fmt.Printf("%d invite codes of batch [%s] created by account ID [%d]\n", len(codes), invitesRequest.Batch, admin)
+
+	respondInviteCodes(gc, `"code" = ANY($1)`, codes)
+}
+
+// AdminRevokeInviteCode stops the code from being redeemed, redemptions so far are kept
+func AdminRevokeInviteCode(gc *gin.Context) {
+	revokeInviteCodes(gc, `"code" = $1`, normalizeCode(gc.Param("code")))
+}
+
+// AdminRevokeInviteBatch stops all codes of the batch from being redeemed
+func AdminRevokeInviteBatch(gc *gin.Context) {
+	revokeInviteCodes(gc, `"batch" = $1`, gc.Param("batch"))
+}
+
+// revokes the codes matching the condition and responds with them
+func revokeInviteCodes(gc *gin.Context, condition string, value string) {
+
+	var (
+		err error
+	)
+
+	_, err = utils.GetPostgrePool().Exec(
+		context.Background(),
+		`UPDATE "invite_code" SET "revoked_at" = NOW() WHERE "revoked_at" IS NULL AND `+condition,
+		value,
+	)
+	if err != nil {
+
+		fmt.Printf("Error revoking invite codes [%s]: %s\n", value, err.Error())
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(response.ResponseErrorDatabase),
+		)
+
+		return
+	}
+
+	fmt.Printf("Invite codes [%s] revoked by account ID [%d]\n", value, getAdminAccount(gc))
+
+	respondInviteCodes(gc, condition, value)
+}
+
+// responds with the invite codes matching the condition
+func respondInviteCodes(gc *gin.Context, condition string, args ...interface{}) {
+
+	var (
+		statusCode int
+
+		invites []InviteCode
+
+		invitesResponse = response.InviteCodesResponse{
+			DefaultResponse: response.DefaultResponse{
+				Status: response.ResponseErrorSuccess,
+			},
+			Codes: []response.InviteCode{},
+		}
+	)
+
+	statusCode, invites = loadInviteCodes(condition, args...)
+	if statusCode != response.ResponseErrorSuccess {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(statusCode),
+		)
+
+		return
+	}
+
+	for i := range invites {
+		invitesResponse.Codes = append(invitesResponse.Codes, createInviteCode(&invites[i]))
+	}
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		invitesResponse,
+	)
+}
@@ -0,0 +1,260 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"PSF-LoginAPI/cache"
+	"PSF-LoginAPI/response"
+	"PSF-LoginAPI/utils"
+)
+
+// launcher version used when no active launchers are known
+const unknownLauncherVersion = "UNK"
+
+// platform of launchers that do not tell theirs
+const (
+	DefaultLauncherPlatform = "windows"
+	DefaultLauncherArch     = "x86"
+)
+
+const launcherQuery = `
+SELECT
+	"version",
+	"active",
+	"released_at",
+	"allow_sha1",
+	"channel",
+	"changelog",
+	"mandatory",
+	"min_os"
+FROM "launcher"
+ORDER BY "released_at" DESC
+`
+
+const launcherArtifactQuery = `
+SELECT
+	"launcher_version",
+	"platform",
+	"arch",
+	"hash",
+	"download_url",
+	"mirrors",
+	"size",
+	"sha256",
+	"signature",
+	"public_key"
+FROM "launcher_artifact"
+ORDER BY "platform", "arch"
+`
+
+type Launcher struct {
+	Version    string    `db:"version"`
+	Active     bool      `db:"active"`
+	ReleasedAt time.Time `db:"released_at"`
+	AllowSHA1  bool      `db:"allow_sha1"`
+	Channel    string    `db:"channel"`
+	Changelog  string    `db:"changelog"`
+	Mandatory  bool      `db:"mandatory"`
+	MinimumOS  string    `db:"min_os"`
+
+	Artifacts []LauncherArtifact `db:"-"`
+}
+
+// LauncherArtifact is the build of a launcher release for one platform
+type LauncherArtifact struct {
+	LauncherVersion string   `db:"launcher_version"`
+	Platform        string   `db:"platform"`
+	Arch            string   `db:"arch"`
+	Hash            string   `db:"hash"`
+	DownloadURL     string   `db:"download_url"`
+	Mirrors         []string `db:"mirrors"`
+	Size            int64    `db:"size"`
+	SHA256          string   `db:"sha256"`
+	Signature       string   `db:"signature"`
+	// base64 encoded Ed25519 key the build signs attestation challenges with
+	PublicKey string `db:"public_key"`
+}
+
+// returns the artifact for the platform, nil if the release has no build for it
+func (launcher *Launcher) getArtifact(platform string, arch string) *LauncherArtifact {
+
+	for i := range launcher.Artifacts {
+		if launcher.Artifacts[i].Platform == platform && launcher.Artifacts[i].Arch == arch {
+			return &launcher.Artifacts[i]
+		}
+	}
+
+	return nil
+}
+
+// returns the launcher release and the artifact with the hash, nil if the hash is unknown
+func getLauncherByHash(hash string) (statusCode int, launcher *Launcher, artifact *LauncherArtifact) {
+
+	var (
+		launchers []Launcher
+	)
+
+	statusCode, launchers = getLaunchers()
+	if statusCode != response.ResponseErrorSuccess {
+		return
+	}
+
+	for i := range launchers {
+		for j := range launchers[i].Artifacts {
+			if launchers[i].Artifacts[j].Hash == hash {
+				return statusCode, &launchers[i], &launchers[i].Artifacts[j]
+			}
+		}
+	}
+
+	return
+}
+
+// all launchers, newest first
+var launcherCache = cache.New[int, []Launcher]("launcher", utils.GetCacheTTL())
+
+func getLaunchers() (statusCode int, launchers []Launcher) {
+
+	launchers = launcherCache.Get(
+		0,
+		func(int) ([]Launcher, bool) {
+			statusCode, launchers = loadLaunchers()
+			return launchers, statusCode == response.ResponseErrorSuccess
+		},
+	)
+
+	return
+}
+
+func loadLaunchers() (statusCode int, launchers []Launcher) {
+
+	var (
+		err error
+
+		rows pgx.Rows
+
+		artifacts []LauncherArtifact
+	)
+
+	rows, err = utils.GetPostgrePool().Query(
+		context.Background(),
+		launcherQuery,
+	)
+	if err != nil {
+		statusCode = response.ResponseErrorDatabase
+
+		fmt.Printf("Error querying launchers from DB: %s\n", err.Error())
+
+		return
+	}
+
+	launchers, err = pgx.CollectRows(rows, pgx.RowToStructByName[Launcher])
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		statusCode = response.ResponseErrorDatabase
+
+		fmt.Printf("Error parsing launchers from DB: %s\n", err.Error())
+
+		return
+	}
+
+	rows, err = utils.GetPostgrePool().Query(
+		context.Background(),
+		launcherArtifactQuery,
+	)
+	if err != nil {
+		statusCode = response.ResponseErrorDatabase
+
+		fmt.Printf("Error querying launcher artifacts from DB: %s\n", err.Error())
+
+		return
+	}
+
+	artifacts, err = pgx.CollectRows(rows, pgx.RowToStructByName[LauncherArtifact])
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		statusCode = response.ResponseErrorDatabase
+
+		fmt.Printf("Error parsing launcher artifacts from DB: %s\n", err.Error())
+
+		return
+	}
+
+	for _, artifact := range artifacts {
+		for i := range launchers {
+			if launchers[i].Version == artifact.LauncherVersion {
+				launchers[i].Artifacts = append(launchers[i].Artifacts, artifact)
+			}
+		}
+	}
+
+	return
+}
+
+// returns true if there are active launchers
+func getLaunchersActive() (hasActiveLaunchers bool) {
+
+	_, launchers := getLaunchers()
+
+	for _, launcher := range launchers {
+		if launcher.Active {
+			return true
+		}
+	}
+
+	return
+}
+
+// returns true if the launcher version is allowed to use SHA1
+func getLauncherAllowsSHA1(version string) bool {
+
+	// tokens issued without active launchers, nothing to enforce
+	if version == "" || version == unknownLauncherVersion {
+		return true
+	}
+
+	_, launchers := getLaunchers()
+
+	for _, launcher := range launchers {
+		if launcher.Version == version && launcher.AllowSHA1 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// returns true if launchers of the channel get the launcher, every channel gets the stable releases
+func isLauncherOnChannel(launcher *Launcher, channel string) bool {
+	return launcher.Channel == channel || launcher.Channel == DefaultLauncherChannel
+}
+
+// IsLauncherUpdateAvailable returns true if the newest active launcher of the channel or stable is newer than the version
+func IsLauncherUpdateAvailable(version utils.LauncherVersion, channel string) bool {
+
+	var (
+		err error
+
+		statusCode int
+
+		latestVersion utils.LauncherVersion
+
+		latest *Launcher
+	)
+
+	statusCode, latest = getLastestLauncherVersion(channel)
+	if statusCode != response.ResponseErrorSuccess {
+		return false
+	}
+
+	latestVersion, err = utils.ParseLauncherVersion(latest.Version)
+	if err != nil {
+		fmt.Printf("Launcher in DB has invalid version: %s\n", err.Error())
+		return false
+	}
+
+	return version.Compare(latestVersion) < 0
+}
@@ -9,7 +9,6 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/jackc/pgx/v5"
-	"golang.org/x/crypto/bcrypt"
 
 	"PSF-LoginAPI/response"
 	"PSF-LoginAPI/utils"
@@ -18,13 +17,12 @@ import (
 type LoginRequest struct {
 	Username     string `json:"username" binding:"required"`
 	Password     string `json:"password" binding:"required"`
-	LauncherHash string `json:"launcher" binding:"required"`
+	LauncherHash string `json:"launcher"`
 	Mode         int64  `json:"mode"`
-}
-
-type LauncherState struct {
-	Version string `db:"version"`
-	Active  bool   `db:"active"`
+	// replaces the launcher hash if launcher attestation is enabled
+	Attestation *LauncherAttestation `json:"attestation"`
+	// stable ID of the device the launcher runs on
+	Device string `json:"device"`
 }
 
 type Account struct {
@@ -33,10 +31,33 @@ type Account struct {
 	Password     string `db:"password"`
 	PasswordHash string `db:"passhash"`
 	Inactive     bool   `db:"inactive"`
+	// login tokens of older session generations are revoked
+	Session       int64   `db:"session_generation"`
+	Email         *string `db:"email"`
+	EmailVerified bool    `db:"email_verified"`
+	// TOTP is enabled
+	TwoFactor bool `db:"two_factor"`
+}
+
+type accountResult struct {
+	statusCode int
+	account    *Account
 }
 
-// getAccount function with constant time enforcement
-var fConstantTimeGetAccount = utils.ConstantTimeCall(utils.ConstantTime, getAccount)
+// everything a login needs once the account and launcher are verified,
+// carried in the challenge token while a second factor is pending
+type loginState struct {
+	Account      int64
+	Session      int64
+	Username     string
+	Mode         int64
+	Launcher     string
+	Platform     string
+	LauncherHash string
+	Device       string
+	// the login was confirmed with the second factor or an emailed code, not part of the challenge
+	Confirmed bool
+}
 
 func Login(gc *gin.Context) {
 
@@ -45,11 +66,12 @@ func Login(gc *gin.Context) {
 
 		statusCode int
 
-		launcherVersionFromHash string
-		token                   string
+		trusted bool
 
 		loginRequest LoginRequest
 		account      *Account
+		launcher     *Launcher
+		artifact     *LauncherArtifact
 	)
 
 	// bind json
@@ -60,8 +82,27 @@ func Login(gc *gin.Context) {
 		return
 	}
 
+	if loginRequest.Device != "" && !deviceIDRegex.MatchString(loginRequest.Device) {
+		fmt.Printf("User [%s] sent invalid device ID\n", loginRequest.Username)
+
+		gc.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	// banned addresses are rejected before their password is checked
+	if !checkAddressBan(gc) {
+		return
+	}
+
 	// get account in constant time
-	statusCode, account = fConstantTimeGetAccount(&loginRequest)
+	statusCode, account, err = getAccountConstantTime(gc.Request.Context(), &loginRequest)
+	if err != nil {
+		fmt.Printf("Login of User [%s] cancelled: %s\n", loginRequest.Username, err.Error())
+
+		gc.Abort()
+		return
+	}
+
 	if statusCode != response.ResponseErrorSuccess {
 
 		gc.IndentedJSON(
@@ -72,8 +113,74 @@ func Login(gc *gin.Context) {
 		return
 	}
 
+	// check account bans, this also catches IP bans issued since the check above
+	if !checkBan(gc, account.ID) {
+		return
+	}
+
 	// check launcher hash
-	statusCode, launcherVersionFromHash = getLauncherVersionFromHash(&loginRequest)
+	statusCode, launcher, artifact = getLoginLauncher(&loginRequest)
+	if statusCode != response.ResponseErrorSuccess {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(statusCode),
+		)
+
+		return
+	}
+
+	state := &loginState{
+		Account:      account.ID,
+		Session:      account.Session,
+		Username:     loginRequest.Username,
+		Mode:         loginRequest.Mode,
+		Launcher:     launcher.Version,
+		Platform:     artifact.Platform + "-" + artifact.Arch,
+		LauncherHash: loginRequest.LauncherHash,
+		Device:       loginRequest.Device,
+	}
+
+	statusCode, trusted = isTrustedDevice(account.ID, state.Device)
+	if statusCode != response.ResponseErrorSuccess {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(statusCode),
+		)
+
+		return
+	}
+
+	// the launcher completes the login with the code,
+	// the second factor is required on every device, trusted devices only skip the emailed code
+	switch {
+	case account.TwoFactor:
+		issueTwoFactorChallenge(gc, state)
+
+	case !trusted && requiresDeviceConfirmation(account):
+		issueDeviceChallenge(gc, state, account)
+
+	default:
+		completeLogin(gc, state)
+	}
+}
+
+// checks the account can use the mode right now and responds with the login token
+func completeLogin(gc *gin.Context, state *loginState) {
+
+	var (
+		err error
+
+		statusCode int
+
+		token string
+
+		roles       []string
+		permissions []string
+	)
+
+	statusCode, roles, permissions = getAccountRoles(state.Account)
 	if statusCode != response.ResponseErrorSuccess {
 
 		gc.IndentedJSON(
@@ -84,20 +191,43 @@ func Login(gc *gin.Context) {
 		return
 	}
 
+	// check mode exists and is open to the account
+	statusCode = checkModeAccess(state.Account, roles, permissions, state.Mode)
+	if statusCode != response.ResponseErrorSuccess {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(statusCode),
+		)
+
+		return
+	}
+
+	if !checkMaintenance(gc, state.Account, permissions, state.Mode) {
+		return
+	}
+
 	fmt.Printf(
-		"User [%s] with ID %d is logging in for mode %d with launcher version %s (%s)\n",
-		loginRequest.Username,
-		account.ID,
-		loginRequest.Mode,
-		launcherVersionFromHash,
-		loginRequest.LauncherHash,
+		"User [%s] with ID %d is logging in for mode %d with launcher version %s for %s (%s)\n",
+		state.Username,
+		state.Account,
+		state.Mode,
+		state.Launcher,
+		state.Platform,
+		state.LauncherHash,
 	)
 
 	// generate token
 	token, err = utils.GenerateToken(
 		&jwt.MapClaims{
-			"account": account.ID,
-			"mode":    loginRequest.Mode,
+			"account":  state.Account,
+			"session":  state.Session,
+			"mode":     state.Mode,
+			"launcher": state.Launcher,
+			"platform": state.Platform,
+
+			"roles":       roles,
+			"permissions": permissions,
 		},
 	)
 	if err != nil {
@@ -112,91 +242,73 @@ func Login(gc *gin.Context) {
 		return
 	}
 
+	recordDevice(gc, state)
+
+	writeAuditEvent(
+		gc,
+		state.Account,
+		AuditEventLogin,
+		map[string]interface{}{
+			"mode":     state.Mode,
+			"launcher": state.Launcher,
+			"platform": state.Platform,
+		},
+	)
+
 	gc.IndentedJSON(
 		http.StatusOK,
 		response.TokenResponse{
 			DefaultResponse: response.DefaultResponse{
 				Status: response.ResponseErrorSuccess,
 			},
-			Token: token,
+			Token:           token,
+			UpdateAvailable: gc.GetBool("updateAvailable"),
 		},
 	)
-
-	return
-}
-
-// returns true if there are active launchers
-func getLaunchersActive() (hasActiveLaunchers bool) {
-
-	var (
-		err error
-
-		rows pgx.Rows
-	)
-
-	rows, err = utils.GetPostgrePool().Query(
-		context.Background(),
-		`SELECT TRUE FROM "launcher" WHERE "active" = TRUE LIMIT 1`,
-	)
-	if err != nil {
-		fmt.Printf("Error getting active launchers from DB: %s\n", err.Error())
-		return
-	}
-
-	hasActiveLaunchers, err = pgx.CollectOneRow(rows, pgx.RowTo[bool])
-	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
-		fmt.Printf("Error parsing active launchers from DB: %s\n", err.Error())
-		return
-	}
-
-	return
 }
 
-func getLauncherVersionFromHash(loginRequest *LoginRequest) (statusCode int, version string) {
+func getLoginLauncher(loginRequest *LoginRequest) (statusCode int, launcher *Launcher, artifact *LauncherArtifact) {
 
 	var (
-		err error
-
-		rows pgx.Rows
-
-		launcherState *LauncherState
+		attestationMode = utils.GetLauncherAttestationMode()
 	)
 
-	version = "UNK"
-
 	// if there are no active launchers at all, just continue
 	if getLaunchersActive() == false {
-		return
-	}
 
-	rows, err = utils.GetPostgrePool().Query(
-		context.Background(),
-		`SELECT "version", "active" FROM "launcher" WHERE "hash" = $1`,
-		loginRequest.LauncherHash,
-	)
-	if err != nil {
-		statusCode = response.ResponseErrorDatabase
+		launcher = &Launcher{
+			Version: unknownLauncherVersion,
+		}
 
-		fmt.Printf("Error querying launcher version from DB: %s\n", err.Error())
+		artifact = &LauncherArtifact{
+			Platform: unknownLauncherVersion,
+			Arch:     unknownLauncherVersion,
+		}
 
 		return
 	}
 
-	launcherState, err = pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByName[LauncherState])
-	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
-		statusCode = response.ResponseErrorDatabase
+	switch {
+	case loginRequest.Attestation != nil && attestationMode != utils.LauncherAttestationDisabled:
+		statusCode, launcher, artifact = getLauncherFromAttestation(loginRequest.Attestation)
 
-		fmt.Printf("Error parsing launcher version from DB: %s\n", err.Error())
+	case attestationMode == utils.LauncherAttestationRequired:
+		fmt.Printf("User [%s] uses launcher without attestation\n", loginRequest.Username)
 
+	default:
+		statusCode, launcher, artifact = getLauncherByHash(loginRequest.LauncherHash)
+	}
+
+	if statusCode != response.ResponseErrorSuccess {
 		return
 	}
 
-	// no launchers with that hash found
-	if errors.Is(err, pgx.ErrNoRows) {
+	// no launchers with that hash or key found
+	if launcher == nil {
 		statusCode = response.ResponseErrorCorruptLauncher
 
 		fmt.Printf(
-			"User [%s] uses launcher with unknown hash: %s\n",
+			"User [%s] uses unknown launcher with hash: %s\n",
 			loginRequest.Username,
 			loginRequest.LauncherHash,
 		)
@@ -205,7 +317,7 @@ func getLauncherVersionFromHash(loginRequest *LoginRequest) (statusCode int, ver
 	}
 
 	// launcher found, check active
-	if launcherState.Active == false {
+	if launcher.Active == false {
 		statusCode = response.ResponseErrorLauncherNoLongerSupported
 
 		return
@@ -214,72 +326,102 @@ func getLauncherVersionFromHash(loginRequest *LoginRequest) (statusCode int, ver
 	return
 }
 
-func getAccount(loginRequest *LoginRequest) (statusCode int, account *Account) {
+// getAccount with constant time enforcement, err is set if the request was cancelled
+func getAccountConstantTime(ctx context.Context, loginRequest *LoginRequest) (statusCode int, account *Account, err error) {
 
 	var (
-		err error
-
-		rows pgx.Rows
+		result accountResult
 	)
 
-	rows, err = utils.GetPostgrePool().Query(
-		context.Background(),
-		`SELECT "id", "username", "password", "passhash", "inactive" FROM "account" WHERE "username" = $1`,
-		loginRequest.Username,
+	result, err = utils.CallConstantTime(
+		ctx,
+		utils.ConstantTime,
+		func(ctx context.Context) accountResult {
+			statusCode, account := getAccount(ctx, loginRequest)
+			return accountResult{statusCode, account}
+		},
 	)
-	if err != nil {
-		statusCode = response.ResponseErrorDatabase
 
-		fmt.Printf("Error getting account from DB: %s\n", err.Error())
+	return result.statusCode, result.account, err
+}
 
+func getAccount(ctx context.Context, loginRequest *LoginRequest) (statusCode int, account *Account) {
+
+	// usernames are unique regardless of case
+	statusCode, account = loadLoginAccount(`LOWER("username") = LOWER($1)`, loginRequest.Username)
+	if statusCode != response.ResponseErrorSuccess {
 		return
 	}
 
-	account, err = pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByName[Account])
-	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
-		statusCode = response.ResponseErrorDatabase
+	// unknown accounts, accounts without a password and wrong passwords fail the same way and take the same time
+	statusCode = checkAccountPassword(ctx, account, loginRequest.Password)
+	if statusCode != response.ResponseErrorSuccess {
 
-		fmt.Printf("Error getting account from DB: %s\n", err.Error())
+		if account == nil {
+			fmt.Printf("Requested account not in DB: %s\n", loginRequest.Username)
+		}
 
-		return
+		return statusCode, nil
 	}
 
-	// account not found
-	if account == nil {
-		statusCode = response.ResponseErrorWrongUsernamePassword
-
-		fmt.Printf("Requested account not in DB: %s\n", loginRequest.Username)
+	// check account inactive
+	if account.Inactive {
+		statusCode = response.ResponseErrorAccountInactive
 
+		fmt.Printf("User [%s] with ID %d tried to login to an inactive account\n", account.Username, account.ID)
 		return
 	}
 
-	// this leaks usernames
-	// if password field is empty the player has not yet logged in via StagingTest since the change
-	if account.Password == "" {
-		statusCode = response.ResponseErrorUseStagingLoginToUpdatePassword
+	return
+}
 
-		fmt.Printf(
-			"User [%s] with ID %d needs to connect via StagingTest to update password\n",
-			account.Username,
-			account.ID,
-		)
-		return
-	}
+// loads the account of a login, replaced in tests to run without database
+var loadLoginAccount = loadAccount
 
-	// check password
-	err = bcrypt.CompareHashAndPassword([]byte(account.Password), []byte(loginRequest.Password))
+// returns the account matching the condition, nil if there is none
+func loadAccount(condition string, args ...interface{}) (statusCode int, account *Account) {
+
+	var (
+		err error
+
+		rows pgx.Rows
+	)
+
+	rows, err = utils.GetPostgrePool().Query(
+		context.Background(),
+		`
+SELECT
+	"id",
+	"username",
+	"password",
+	"passhash",
+	"inactive",
+	"session_generation",
+	"email",
+	"email_verified_at" IS NOT NULL AS "email_verified",
+	EXISTS (
+		SELECT 1
+		FROM "account_totp"
+		WHERE "account_totp"."account_id" = "account"."id" AND "account_totp"."enabled_at" IS NOT NULL
+	) AS "two_factor"
+FROM "account"
+WHERE `+condition,
+		args...,
+	)
 	if err != nil {
-		statusCode = response.ResponseErrorWrongUsernamePassword
+		statusCode = response.ResponseErrorDatabase
+
+		fmt.Printf("Error getting account from DB: %s\n", err.Error())
 
-		fmt.Printf("Login as User [%s] with ID %d failed password check\n", account.Username, account.ID)
 		return
 	}
 
-	// check account inactive
-	if account.Inactive {
-		statusCode = response.ResponseErrorAccountInactive
+	account, err = pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByName[Account])
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		statusCode = response.ResponseErrorDatabase
+
+		fmt.Printf("Error getting account from DB: %s\n", err.Error())
 
-		fmt.Printf("User [%s] with ID %d tried to login to an inactive account\n", account.Username, account.ID)
 		return
 	}
 
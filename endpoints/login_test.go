@@ -0,0 +1,140 @@
+package endpoints
+
+import (
+	"context"
+	"math"
+	"sort"
+	"testing"
+	"time"
+
+	"PSF-LoginAPI/password"
+	"PSF-LoginAPI/response"
+	"PSF-LoginAPI/utils"
+)
+
+// samples per case, every case verifies a bcrypt hash of the default cost
+const loginTimingSamples = 30
+
+// two sided critical value of Welch's t-test for a significance of 0.001 at about 50 degrees of freedom
+const loginTimingCriticalT = 3.5
+
+// TestLoginFailureTiming checks that failed logins of unknown accounts, wrong passwords,
+// accounts without password and accounts with only the legacy hash take the same time
+func TestLoginFailureTiming(t *testing.T) {
+
+	if testing.Short() {
+		t.Skip("timing test skipped in short mode")
+	}
+
+	var (
+		hasher = utils.GetPasswordHasher()
+	)
+
+	hash, err := hasher.Hash("correct password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// legacy hashes are plain bcrypt of the default cost
+	legacyHash, err := (&password.Bcrypt{}).Hash("correct password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accounts := map[string]*Account{
+		"unknown":    nil,
+		"wrong":      {ID: 1, Username: "wrong", Password: hash},
+		"nopassword": {ID: 2, Username: "nopassword"},
+		"unmigrated": {ID: 3, Username: "unmigrated", PasswordHash: legacyHash},
+	}
+
+	loadLoginAccount = func(condition string, args ...interface{}) (int, *Account) {
+		return response.ResponseErrorSuccess, accounts[args[0].(string)]
+	}
+	defer func() {
+		loadLoginAccount = loadAccount
+	}()
+
+	// create the dummy hash and warm up before measuring
+	utils.GetDummyPasswordHash()
+
+	samples := make(map[string][]float64)
+	names := []string{"unknown", "wrong", "nopassword", "unmigrated"}
+
+	// interleaved, so load changes on the machine affect all cases alike
+	for i := 0; i < loginTimingSamples+1; i++ {
+		for _, name := range names {
+
+			start := time.Now()
+
+			statusCode, account := getAccount(context.Background(), &LoginRequest{Username: name, Password: "wrong password"})
+			if statusCode != response.ResponseErrorWrongUsernamePassword || account != nil {
+				t.Fatalf("login of %s returned %d, expected wrong username or password", name, statusCode)
+			}
+
+			if i > 0 {
+				samples[name] = append(samples[name], float64(time.Since(start).Microseconds()))
+			}
+		}
+	}
+
+	for _, name := range names {
+
+		if name == "wrong" {
+			continue
+		}
+
+		tValue := welchT(trimSlowest(samples[name]), trimSlowest(samples["wrong"]))
+
+		t.Logf("%s: mean %.0fµs, wrong password: mean %.0fµs, t = %.2f", name, mean(samples[name]), mean(samples["wrong"]), tValue)
+
+		if math.Abs(tValue) > loginTimingCriticalT {
+			t.Errorf("failed login of %s takes different time than a wrong password, t = %.2f", name, tValue)
+		}
+	}
+}
+
+// drops the slowest tenth of the samples, they are mostly scheduling noise
+func trimSlowest(samples []float64) []float64 {
+
+	sorted := append([]float64(nil), samples...)
+	sort.Float64s(sorted)
+
+	return sorted[:len(sorted)-len(sorted)/10]
+}
+
+func mean(samples []float64) float64 {
+
+	var sum float64
+
+	for _, sample := range samples {
+		sum += sample
+	}
+
+	return sum / float64(len(samples))
+}
+
+func variance(samples []float64) float64 {
+
+	var (
+		sum     float64
+		average = mean(samples)
+	)
+
+	for _, sample := range samples {
+		sum += (sample - average) * (sample - average)
+	}
+
+	return sum / float64(len(samples)-1)
+}
+
+// t statistic of Welch's t-test for samples of unequal variance
+func welchT(a []float64, b []float64) float64 {
+
+	standardError := math.Sqrt(variance(a)/float64(len(a)) + variance(b)/float64(len(b)))
+	if standardError == 0 {
+		return 0
+	}
+
+	return (mean(a) - mean(b)) / standardError
+}
@@ -0,0 +1,353 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+
+	"PSF-LoginAPI/cache"
+	"PSF-LoginAPI/response"
+	"PSF-LoginAPI/utils"
+)
+
+type MaintenanceWindow struct {
+	ID int64 `db:"id"`
+	// nil for global maintenance
+	Mode     *int64    `db:"mode"`
+	StartsAt time.Time `db:"starts_at"`
+	EndsAt   time.Time `db:"ends_at"`
+	Message  string    `db:"message"`
+}
+
+type AdminMaintenanceWindowRequest struct {
+	Mode     *int64 `json:"mode"`
+	StartsAt int64  `json:"startsAt" binding:"required"`
+	EndsAt   int64  `json:"endsAt" binding:"required"`
+	Message  string `json:"message"`
+}
+
+// windows that did not end yet, ordered by start
+var maintenanceCache = cache.New[int, []MaintenanceWindow]("maintenance", utils.GetCacheTTL())
+
+func (window *MaintenanceWindow) isActive(now time.Time) bool {
+	return !now.Before(window.StartsAt) && now.Before(window.EndsAt)
+}
+
+// returns true if the window applies to the mode
+func (window *MaintenanceWindow) appliesTo(mode int64) bool {
+	return window.Mode == nil || *window.Mode == mode
+}
+
+// Maintenance lists current and upcoming maintenance windows,
+// only global ones and the ones of the mode query parameter if it is set
+func Maintenance(gc *gin.Context) {
+
+	var (
+		err error
+
+		statusCode int
+
+		mode    int64
+		hasMode bool
+
+		windows []MaintenanceWindow
+
+		now = time.Now()
+
+		windowsResponse = response.MaintenanceWindowsResponse{
+			DefaultResponse: response.DefaultResponse{
+				Status: response.ResponseErrorSuccess,
+			},
+			Windows: []response.MaintenanceWindow{},
+		}
+	)
+
+	if gc.Query("mode") != "" {
+
+		mode, err = strconv.ParseInt(gc.Query("mode"), 10, 64)
+		if err != nil {
+			gc.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+
+		hasMode = true
+	}
+
+	statusCode, windows = getMaintenanceWindows()
+	if statusCode != response.ResponseErrorSuccess {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(statusCode),
+		)
+
+		return
+	}
+
+	for i := range windows {
+
+		if !now.Before(windows[i].EndsAt) || (hasMode && !windows[i].appliesTo(mode)) {
+			continue
+		}
+
+		windowsResponse.Windows = append(windowsResponse.Windows, createMaintenanceWindow(&windows[i], now))
+	}
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		windowsResponse,
+	)
+}
+
+func createMaintenanceWindow(window *MaintenanceWindow, now time.Time) response.MaintenanceWindow {
+	return response.MaintenanceWindow{
+		ID:       window.ID,
+		Mode:     window.Mode,
+		StartsAt: window.StartsAt.Unix(),
+		EndsAt:   window.EndsAt.Unix(),
+		Message:  window.Message,
+		Active:   window.isActive(now),
+	}
+}
+
+// writes the maintenance response if the mode is in maintenance right now,
+// returns false if the request has to stop
+func checkMaintenance(gc *gin.Context, account int64, permissions []string, modeID int64) bool {
+
+	var (
+		statusCode int
+
+		now = time.Now()
+
+		mode    *Mode
+		windows []MaintenanceWindow
+		active  *MaintenanceWindow
+	)
+
+	if utils.HasPermission(permissions, utils.PermissionMaintenanceBypass) {
+		return true
+	}
+
+	statusCode, windows = getMaintenanceWindows()
+	if statusCode == response.ResponseErrorSuccess {
+		statusCode, mode = getMode(modeID)
+	}
+	if statusCode != response.ResponseErrorSuccess {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(statusCode),
+		)
+
+		return false
+	}
+
+	for i := range windows {
+
+		if !windows[i].isActive(now) || !windows[i].appliesTo(modeID) {
+			continue
+		}
+
+		// report the window that lasts the longest
+		if active == nil || windows[i].EndsAt.After(active.EndsAt) {
+			active = &windows[i]
+		}
+	}
+
+	switch {
+	case active != nil:
+		statusCode = response.ResponseErrorModeMaintenance
+		if active.Mode == nil {
+			statusCode = response.ResponseErrorMaintenance
+		}
+
+		fmt.Printf("Account ID [%d] rejected for mode %d during maintenance window %d\n", account, modeID, active.ID)
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.MaintenanceResponse{
+				DefaultResponse: response.DefaultResponse{
+					Status: statusCode,
+				},
+				Message: active.Message,
+				EndsAt:  active.EndsAt.Unix(),
+			},
+		)
+
+		return false
+
+	// mode is in maintenance until further notice
+	case mode != nil && mode.Maintenance:
+		fmt.Printf("Account ID [%d] rejected for mode %d in maintenance\n", account, modeID)
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.MaintenanceResponse{
+				DefaultResponse: response.DefaultResponse{
+					Status: response.ResponseErrorModeMaintenance,
+				},
+			},
+		)
+
+		return false
+	}
+
+	return true
+}
+
+func getMaintenanceWindows() (statusCode int, windows []MaintenanceWindow) {
+
+	windows = maintenanceCache.Get(
+		0,
+		func(int) ([]MaintenanceWindow, bool) {
+			statusCode, windows = loadMaintenanceWindows()
+			return windows, statusCode == response.ResponseErrorSuccess
+		},
+	)
+
+	return
+}
+
+func loadMaintenanceWindows() (statusCode int, windows []MaintenanceWindow) {
+
+	var (
+		err error
+
+		rows pgx.Rows
+	)
+
+	rows, err = utils.GetPostgrePool().Query(
+		context.Background(),
+		`
+SELECT "id", "mode", "starts_at", "ends_at", "message"
+FROM "maintenance_window"
+WHERE "ends_at" > NOW()
+ORDER BY "starts_at"
+`,
+	)
+	if err != nil {
+		statusCode = response.ResponseErrorDatabase
+
+		fmt.Printf("Error querying maintenance windows from DB: %s\n", err.Error())
+
+		return
+	}
+
+	windows, err = pgx.CollectRows(rows, pgx.RowToStructByName[MaintenanceWindow])
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		statusCode = response.ResponseErrorDatabase
+
+		fmt.Printf("Error parsing maintenance windows from DB: %s\n", err.Error())
+
+		return
+	}
+
+	return
+}
+
+func AdminCreateMaintenanceWindow(gc *gin.Context) {
+
+	var (
+		err error
+
+		id int64
+
+		windowRequest AdminMaintenanceWindowRequest
+	)
+
+	err = gc.BindJSON(&windowRequest)
+	if err != nil {
+		fmt.Println("Could not parse request body as POST AdminCreateMaintenanceWindow")
+
+		return
+	}
+
+	if windowRequest.EndsAt <= windowRequest.StartsAt {
+		gc.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	err = utils.GetPostgrePool().QueryRow(
+		context.Background(),
+		`
+INSERT INTO "maintenance_window" ("mode", "starts_at", "ends_at", "message")
+VALUES ($1, to_timestamp($2::BIGINT), to_timestamp($3::BIGINT), $4)
+RETURNING "id"
+`,
+		windowRequest.Mode,
+		windowRequest.StartsAt,
+		windowRequest.EndsAt,
+		windowRequest.Message,
+	).Scan(&id)
+	if err != nil {
+
+		fmt.Printf("Error creating maintenance window: %s\n", err.Error())
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(response.ResponseErrorDatabase),
+		)
+
+		return
+	}
+
+	fmt.Printf("Maintenance window %d created by account ID [%d]\n", id, getAdminAccount(gc))
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		response.IDResponse{
+			DefaultResponse: response.DefaultResponse{
+				Status: response.ResponseErrorSuccess,
+			},
+			ID: id,
+		},
+	)
+}
+
+func AdminDeleteMaintenanceWindow(gc *gin.Context) {
+
+	var (
+		err error
+
+		ok bool
+
+		id int64
+	)
+
+	id, ok = getIDParam(gc, "id")
+	if !ok {
+		return
+	}
+
+	_, err = utils.GetPostgrePool().Exec(
+		context.Background(),
+		`DELETE FROM "maintenance_window" WHERE "id" = $1`,
+		id,
+	)
+	if err != nil {
+
+		fmt.Printf("Error deleting maintenance window %d: %s\n", id, err.Error())
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(response.ResponseErrorDatabase),
+		)
+
+		return
+	}
+
+	fmt.Printf("Maintenance window %d deleted by account ID [%d]\n", id, getAdminAccount(gc))
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		response.DefaultResponse{
+			Status: response.ResponseErrorSuccess,
+		},
+	)
+}
@@ -0,0 +1,262 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"PSF-LoginAPI/cache"
+	"PSF-LoginAPI/response"
+	"PSF-LoginAPI/utils"
+)
+
+// Manifest file rules
+const (
+	// file has to be present and match one of the allowed hashes
+	FileRuleRequired = "required"
+	// file is only validated if it is present
+	FileRuleOptional = "optional"
+	// file has to be present, its content is not validated
+	FileRulePresence = "presence"
+)
+
+const fileHashQuery = `
+SELECT
+	"file",
+	"hash",
+	"rule",
+	"glob",
+	COALESCE(
+		(
+			SELECT array_agg(alternate."hash")
+			FROM filehash_alternate AS alternate
+			WHERE alternate.mode = filehash.mode
+			AND alternate.file = filehash.file
+		),
+		'{}'::TEXT[]
+	) AS "alternates"
+FROM filehash
+WHERE
+		"mode" = $1
+	OR (
+			"mode" = 0
+		AND
+			NOT EXISTS (
+				SELECT 1
+				FROM filehash AS selectedMode
+				WHERE selectedMode.mode = $1
+				AND selectedMode.file = filehash.file
+			)
+	)
+ORDER BY "file";
+`
+
+type Manifest struct {
+	Rules []ManifestRule
+	// changes whenever any of the rules change
+	ETag string
+}
+
+type ManifestRule struct {
+	File       string   `db:"file"`
+	Hash       string   `db:"hash"`
+	Rule       string   `db:"rule"`
+	Glob       bool     `db:"glob"`
+	Alternates []string `db:"alternates"`
+}
+
+// legacy rules are the ones older launchers know how to validate,
+// plain required files hashed into the aggregate file hash
+func (rule *ManifestRule) isLegacy() bool {
+	return rule.Rule == FileRuleRequired && !rule.Glob
+}
+
+// returns true if the file hash is the expected hash or one of the alternates
+func (rule *ManifestRule) allowsHash(fileHash string) bool {
+
+	// nothing to compare against, any content is fine
+	if rule.Hash == "" && len(rule.Alternates) == 0 {
+		return true
+	}
+
+	if strings.EqualFold(rule.Hash, fileHash) {
+		return true
+	}
+
+	for _, alternate := range rule.Alternates {
+		if strings.EqualFold(alternate, fileHash) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// returns the hashes of all reported files the rule applies to
+func (rule *ManifestRule) matchReported(reported map[string]string) (fileHashes []string) {
+
+	var (
+		pattern = normalizeManifestPath(rule.File)
+	)
+
+	if !rule.Glob {
+
+		fileHash, exists := reported[pattern]
+		if exists {
+			fileHashes = append(fileHashes, fileHash)
+		}
+
+		return
+	}
+
+	for file, fileHash := range reported {
+
+		matched, err := path.Match(pattern, file)
+		if err != nil {
+			fmt.Printf("Invalid manifest glob pattern [%s]: %s\n", rule.File, err.Error())
+			return
+		}
+
+		if matched {
+			fileHashes = append(fileHashes, fileHash)
+		}
+	}
+
+	return
+}
+
+// file names are compared case-insensitive with forward slashes
+func normalizeManifestPath(file string) string {
+	return strings.ToLower(strings.ReplaceAll(file, `\`, "/"))
+}
+
+// returns the files and patterns of all rules the reported files violate
+func evaluateManifest(rules []ManifestRule, reportedFiles map[string]string) (failed []string) {
+
+	var (
+		reported = make(map[string]string, len(reportedFiles))
+	)
+
+	for file, fileHash := range reportedFiles {
+		reported[normalizeManifestPath(file)] = fileHash
+	}
+
+	for i := range rules {
+
+		var (
+			rule        = &rules[i]
+			fileHashes  = rule.matchReported(reported)
+			ruleFailed  = false
+			mustBeFound = rule.Rule != FileRuleOptional
+		)
+
+		if mustBeFound && len(fileHashes) == 0 {
+			ruleFailed = true
+		}
+
+		if rule.Rule != FileRulePresence {
+			for _, fileHash := range fileHashes {
+				if !rule.allowsHash(fileHash) {
+					ruleFailed = true
+				}
+			}
+		}
+
+		if ruleFailed {
+			failed = append(failed, rule.File)
+		}
+	}
+
+	return
+}
+
+// converts manifest rules into the representation sent to the launcher
+func createFileRules(rules []ManifestRule) (fileRules []response.FileRule) {
+
+	fileRules = make([]response.FileRule, 0, len(rules))
+
+	for _, rule := range rules {
+		fileRules = append(
+			fileRules,
+			response.FileRule{
+				File: rule.File,
+				Rule: rule.Rule,
+				Glob: rule.Glob,
+			},
+		)
+	}
+
+	return
+}
+
+// manifests per mode
+var manifestCache = cache.New[int64, *Manifest]("manifest", utils.GetCacheTTL())
+
+func getManifestForMode(mode int64) (statusCode int, manifest *Manifest) {
+
+	manifest = manifestCache.Get(
+		mode,
+		func(mode int64) (*Manifest, bool) {
+			statusCode, manifest = loadManifestForMode(mode)
+			return manifest, statusCode == response.ResponseErrorSuccess
+		},
+	)
+
+	return
+}
+
+func loadManifestForMode(mode int64) (statusCode int, manifest *Manifest) {
+
+	var (
+		err error
+
+		rows pgx.Rows
+
+		rules    []ManifestRule
+		tagParts []string
+	)
+
+	rows, err = utils.GetPostgrePool().Query(
+		context.Background(),
+		fileHashQuery,
+		mode,
+	)
+	if err != nil {
+		statusCode = response.ResponseErrorDatabase
+
+		fmt.Printf("Error querying manifest for mode %d from DB: %s\n", mode, err.Error())
+
+		return
+	}
+
+	rules, err = pgx.CollectRows(rows, pgx.RowToStructByName[ManifestRule])
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		statusCode = response.ResponseErrorDatabase
+
+		fmt.Printf("Error parsing manifest for mode %d from DB: %s\n", mode, err.Error())
+
+		return
+	}
+
+	for _, rule := range rules {
+		tagParts = append(
+			tagParts,
+			rule.File,
+			rule.Hash,
+			rule.Rule,
+			fmt.Sprint(rule.Glob),
+			strings.Join(rule.Alternates, ","),
+		)
+	}
+
+	manifest = &Manifest{
+		Rules: rules,
+		ETag:  utils.CreateETag(tagParts...),
+	}
+
+	return
+}
@@ -0,0 +1,119 @@
+package endpoints
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEvaluateManifest(t *testing.T) {
+
+	var (
+		rules = []ManifestRule{
+			{File: "planetside.exe", Hash: "AAAA", Rule: FileRuleRequired, Alternates: []string{"BBBB"}},
+			{File: "Data/optional.dat", Hash: "CCCC", Rule: FileRuleOptional},
+			{File: "pslogin.dll", Hash: "DDDD", Rule: FileRulePresence},
+			{File: "maps/*.zip", Hash: "EEEE", Rule: FileRuleRequired, Glob: true},
+		}
+	)
+
+	tests := []struct {
+		name     string
+		reported map[string]string
+		failed   []string
+	}{
+		{
+			name: "all valid",
+			reported: map[string]string{
+				"planetside.exe": "aaaa",
+				"pslogin.dll":    "anything",
+				"maps/a.zip":     "EEEE",
+			},
+		},
+		{
+			name: "alternate hash and backslashes",
+			reported: map[string]string{
+				"PlanetSide.exe":     "BBBB",
+				"Data\\optional.dat": "CCCC",
+				"pslogin.dll":        "",
+				"MAPS\\b.zip":        "EEEE",
+			},
+		},
+		{
+			name: "required file missing",
+			reported: map[string]string{
+				"pslogin.dll": "",
+				"maps/a.zip":  "EEEE",
+			},
+			failed: []string{"planetside.exe"},
+		},
+		{
+			name: "wrong hashes",
+			reported: map[string]string{
+				"planetside.exe":    "FFFF",
+				"data/optional.dat": "FFFF",
+				"pslogin.dll":       "",
+				"maps/a.zip":        "EEEE",
+			},
+			failed: []string{"planetside.exe", "Data/optional.dat"},
+		},
+		{
+			name: "presence file missing",
+			reported: map[string]string{
+				"planetside.exe": "AAAA",
+				"maps/a.zip":     "EEEE",
+			},
+			failed: []string{"pslogin.dll"},
+		},
+		{
+			name: "one glob match wrong",
+			reported: map[string]string{
+				"planetside.exe": "AAAA",
+				"pslogin.dll":    "",
+				"maps/a.zip":     "EEEE",
+				"maps/b.zip":     "FFFF",
+			},
+			failed: []string{"maps/*.zip"},
+		},
+		{
+			name: "glob without match",
+			reported: map[string]string{
+				"planetside.exe": "AAAA",
+				"pslogin.dll":    "",
+				"maps/a.txt":     "EEEE",
+			},
+			failed: []string{"maps/*.zip"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			failed := evaluateManifest(rules, test.reported)
+
+			if !reflect.DeepEqual(failed, test.failed) {
+				t.Errorf("failed rules %v, expected %v", failed, test.failed)
+			}
+		})
+	}
+}
+
+func TestManifestRuleAllowsHash(t *testing.T) {
+
+	tests := []struct {
+		rule     ManifestRule
+		fileHash string
+		allowed  bool
+	}{
+		{ManifestRule{}, "anything", true},
+		{ManifestRule{Hash: "abcd"}, "ABCD", true},
+		{ManifestRule{Hash: "abcd"}, "abce", false},
+		{ManifestRule{Alternates: []string{"1234"}}, "1234", true},
+		{ManifestRule{Hash: "abcd", Alternates: []string{"1234"}}, "5678", false},
+	}
+
+	for _, test := range tests {
+		if allowed := test.rule.allowsHash(test.fileHash); allowed != test.allowed {
+			t.Errorf("rule %+v allows %s: %v, expected %v", test.rule, test.fileHash, allowed, test.allowed)
+		}
+	}
+}
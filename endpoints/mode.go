@@ -0,0 +1,215 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+
+	"PSF-LoginAPI/cache"
+	"PSF-LoginAPI/response"
+	"PSF-LoginAPI/utils"
+)
+
+type Mode struct {
+	ID           int64    `db:"id"`
+	Name         string   `db:"name"`
+	Description  string   `db:"description"`
+	Enabled      bool     `db:"enabled"`
+	WorldHost    string   `db:"world_host"`
+	WorldPort    int32    `db:"world_port"`
+	RequiredRole *string  `db:"required_role"`
+	Maintenance  bool     `db:"maintenance"`
+	LaunchArgs   []string `db:"launch_args"`
+	// only accounts with the permission can use the mode
+	RequiredPermission *string `db:"required_permission"`
+}
+
+// all modes, ordered by ID
+var modeCache = cache.New[int, []Mode]("mode", utils.GetCacheTTL())
+
+// Modes lists the modes available to the caller
+func Modes(gc *gin.Context) {
+
+	var (
+		statusCode int
+
+		modes []Mode
+
+		roles, permissions = getClaimsPermissions(gc)
+
+		modesResponse = response.ModesResponse{
+			DefaultResponse: response.DefaultResponse{
+				Status: response.ResponseErrorSuccess,
+			},
+			Modes: []response.Mode{},
+		}
+	)
+
+	statusCode, modes = getModes()
+	if statusCode != response.ResponseErrorSuccess {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(statusCode),
+		)
+
+		return
+	}
+
+	for i := range modes {
+
+		if !modes[i].Enabled || !canAccessMode(&modes[i], roles, permissions) {
+			continue
+		}
+
+		modesResponse.Modes = append(
+			modesResponse.Modes,
+			response.Mode{
+				ID:          modes[i].ID,
+				Name:        modes[i].Name,
+				Description: modes[i].Description,
+				Maintenance: modes[i].Maintenance,
+			},
+		)
+	}
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		modesResponse,
+	)
+}
+
+// returns the mode with the ID, nil if it does not exist
+func getMode(id int64) (statusCode int, mode *Mode) {
+
+	var (
+		modes []Mode
+	)
+
+	statusCode, modes = getModes()
+	if statusCode != response.ResponseErrorSuccess {
+		return
+	}
+
+	for i := range modes {
+		if modes[i].ID == id {
+			return statusCode, &modes[i]
+		}
+	}
+
+	return
+}
+
+// returns if an account with the roles and permissions may use the mode
+func canAccessMode(mode *Mode, roles []string, permissions []string) bool {
+
+	var (
+		hasRole = mode.RequiredRole == nil || utils.HasPermission(permissions, utils.PermissionAll)
+	)
+
+	// invited to the mode
+	if utils.HasPermission(permissions, utils.ModeGrantPermission(mode.ID)) {
+		return true
+	}
+
+	for _, role := range roles {
+		if mode.RequiredRole != nil && role == *mode.RequiredRole {
+			hasRole = true
+		}
+	}
+
+	if !hasRole {
+		return false
+	}
+
+	if mode.RequiredPermission != nil && !utils.HasPermission(permissions, *mode.RequiredPermission) {
+		return false
+	}
+
+	return true
+}
+
+// checks the account can log in to the mode right now
+func checkModeAccess(account int64, roles []string, permissions []string, modeID int64) (statusCode int) {
+
+	var (
+		mode *Mode
+	)
+
+	statusCode, mode = getMode(modeID)
+	if statusCode != response.ResponseErrorSuccess {
+		return
+	}
+
+	switch {
+	case mode == nil:
+		statusCode = response.ResponseErrorUnknownMode
+
+		fmt.Printf("Account ID [%d] requested unknown mode %d\n", account, modeID)
+
+	case !mode.Enabled:
+		statusCode = response.ResponseErrorModeDisabled
+
+		fmt.Printf("Account ID [%d] requested disabled mode %d\n", account, modeID)
+
+	case !canAccessMode(mode, roles, permissions):
+		statusCode = response.ResponseErrorModeAccessDenied
+
+		fmt.Printf("Account ID [%d] is not allowed to use mode %d\n", account, modeID)
+	}
+
+	return
+}
+
+func getModes() (statusCode int, modes []Mode) {
+
+	modes = modeCache.Get(
+		0,
+		func(int) ([]Mode, bool) {
+			statusCode, modes = loadModes()
+			return modes, statusCode == response.ResponseErrorSuccess
+		},
+	)
+
+	return
+}
+
+func loadModes() (statusCode int, modes []Mode) {
+
+	var (
+		err error
+
+		rows pgx.Rows
+	)
+
+	rows, err = utils.GetPostgrePool().Query(
+		context.Background(),
+		`
+SELECT "id", "name", "description", "enabled", "world_host", "world_port", "required_role", "maintenance", "launch_args", "required_permission"
+FROM "mode"
+ORDER BY "id"
+`,
+	)
+	if err != nil {
+		statusCode = response.ResponseErrorDatabase
+
+		fmt.Printf("Error querying modes from DB: %s\n", err.Error())
+
+		return
+	}
+
+	modes, err = pgx.CollectRows(rows, pgx.RowToStructByName[Mode])
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		statusCode = response.ResponseErrorDatabase
+
+		fmt.Printf("Error parsing modes from DB: %s\n", err.Error())
+
+		return
+	}
+
+	return
+}
@@ -0,0 +1,143 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"PSF-LoginAPI/password"
+	"PSF-LoginAPI/response"
+	"PSF-LoginAPI/utils"
+)
+
+// returns the hash of the account the password is checked against, false if the account has none,
+// accounts that did not login since the password change only have the legacy hash
+func getAccountPasswordHash(account *Account) (string, bool) {
+
+	switch {
+	case account.Password != "":
+		return account.Password, true
+	case account.PasswordHash != "":
+		return password.Legacy(account.PasswordHash), true
+	}
+
+	return "", false
+}
+
+// checks the password of the account and rehashes it if needed.
+// Without an account or password hash the password is checked against a dummy hash,
+// so the check takes as long and fails the same way as a wrong password.
+func checkAccountPassword(ctx context.Context, account *Account, plainPassword string) (statusCode int) {
+
+	var (
+		err error
+
+		ok     bool
+		rehash bool
+
+		hash   string
+		exists bool
+	)
+
+	if account != nil {
+		hash, exists = getAccountPasswordHash(account)
+	}
+
+	if !exists {
+		hash = utils.GetDummyPasswordHash()
+	}
+
+	ok, rehash, err = utils.GetPasswordPool().Verify(ctx, utils.GetPasswordHasher(), hash, plainPassword)
+	if errors.Is(err, password.ErrOverloaded) {
+		statusCode = response.ResponseErrorInternalServerBusy
+
+		fmt.Println("Login rejected, too many logins")
+		return
+	}
+	if err != nil {
+		fmt.Printf("Error checking password: %s\n", err.Error())
+	}
+
+	switch {
+	case account == nil:
+		statusCode = response.ResponseErrorWrongUsernamePassword
+		return
+
+	case !exists:
+		statusCode = response.ResponseErrorWrongUsernamePassword
+
+		fmt.Printf("User [%s] with ID %d has no password hash\n", account.Username, account.ID)
+		return
+
+	case err != nil || !ok:
+		statusCode = response.ResponseErrorWrongUsernamePassword
+
+		fmt.Printf("Login as User [%s] with ID %d failed password check\n", account.Username, account.ID)
+		return
+	}
+
+	// move the account to the current algorithm and parameters
+	if rehash {
+		rehashPassword(ctx, account, plainPassword)
+	}
+
+	return
+}
+
+// replaces the password hash of the account with one of the configured algorithm and parameters,
+// the hash is kept if the password pool is too busy or it can not be replaced and the next login tries again
+func rehashPassword(ctx context.Context, account *Account, plainPassword string) {
+
+	var (
+		err error
+
+		hash string
+
+		hasher = utils.GetPasswordHasher()
+	)
+
+	hash, err = utils.GetPasswordPool().Hash(ctx, hasher, plainPassword)
+	if errors.Is(err, password.ErrOverloaded) {
+		return
+	}
+	if err == nil {
+		err = pgx.BeginFunc(
+			context.Background(),
+			utils.GetPostgrePool(),
+			func(tx pgx.Tx) error {
+
+				var current string
+
+				// another login may have replaced the hash already
+				err := tx.QueryRow(
+					context.Background(),
+					`SELECT "password" FROM "account" WHERE "id" = $1 FOR UPDATE`,
+					account.ID,
+				).Scan(&current)
+				if err != nil || current != account.Password {
+					return err
+				}
+
+				_, err = tx.Exec(
+					context.Background(),
+					`UPDATE "account" SET "password" = $2 WHERE "id" = $1`,
+					account.ID,
+					hash,
+				)
+
+				return err
+			},
+		)
+	}
+	if err != nil {
+		fmt.Printf("Error rehashing password of account %d: %s\n", account.ID, err.Error())
+
+		return
+	}
+
+	fmt.Printf("User [%s] with ID %d password rehashed with %s\n", account.Username, account.ID, hasher.Name())
+
+	account.Password = hash
+}
@@ -0,0 +1,217 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"PSF-LoginAPI/password"
+	"PSF-LoginAPI/response"
+	"PSF-LoginAPI/utils"
+)
+
+// letters, digits, underscores and hyphens, starting with a letter
+var usernameRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]{2,31}$`)
+
+// names that could be mistaken for staff or the project
+var reservedUsernames = []string{
+	"admin",
+	"administrator",
+	"gm",
+	"moderator",
+	"psforever",
+	"root",
+	"staff",
+	"support",
+	"system",
+}
+
+type RegisterRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	// required if registration needs an invite
+	InviteCode string `json:"inviteCode"`
+	// optional, a verification code is sent to it
+	Email string `json:"email"`
+}
+
+// Register creates an account with the username and password
+func Register(gc *gin.Context) {
+
+	var (
+		err error
+
+		statusCode int
+
+		hash string
+
+		account int64
+
+		registerRequest RegisterRequest
+
+		inviteRequired = utils.GetRegistrationMode() == utils.RegistrationInvite
+	)
+
+	err = gc.BindJSON(&registerRequest)
+	if err != nil {
+		fmt.Println("Could not parse request body as POST Register")
+
+		return
+	}
+
+	err = checkUsername(registerRequest.Username)
+	if err != nil {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponseWithText(response.ResponseErrorInvalidUsername, err.Error()),
+		)
+
+		return
+	}
+
+	if !checkPasswordPolicy(gc, registerRequest.Password, registerRequest.Username) {
+		return
+	}
+
+	if registerRequest.Email != "" && !isValidEmail(registerRequest.Email) {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(response.ResponseErrorInvalidEmail),
+		)
+
+		return
+	}
+
+	if inviteRequired && registerRequest.InviteCode == "" {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(response.ResponseErrorInvalidInviteCode),
+		)
+
+		return
+	}
+
+	hash, err = utils.GetPasswordPool().Hash(gc.Request.Context(), utils.GetPasswordHasher(), registerRequest.Password)
+	if err != nil {
+
+		statusCode = response.ResponseErrorInternalServerBusy
+		if !errors.Is(err, password.ErrOverloaded) {
+			statusCode = response.ResponseErrorInternalPasswordHashFailed
+
+			fmt.Printf("Error hashing password of new account [%s]: %s\n", registerRequest.Username, err.Error())
+		}
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(statusCode),
+		)
+
+		return
+	}
+
+	err = pgx.BeginFunc(
+		context.Background(),
+		utils.GetPostgrePool(),
+		func(tx pgx.Tx) error {
+
+			err := tx.QueryRow(
+				context.Background(),
+				`INSERT INTO "account" ("username", "password", "passhash", "email") VALUES ($1, $2, '', NULLIF($3, '')) RETURNING "id"`,
+				registerRequest.Username,
+				hash,
+				registerRequest.Email,
+			).Scan(&account)
+			if err != nil || !inviteRequired {
+				return err
+			}
+
+			_, err = redeemInviteCode(tx, registerRequest.InviteCode, InviteTargetRegistration, account)
+
+			return err
+		},
+	)
+
+	var pgError *pgconn.PgError
+
+	switch {
+	case errors.Is(err, errInvalidInviteCode), errors.Is(err, errInviteCodeRedeemed):
+		statusCode = getInviteStatusCode(err)
+
+	case errors.As(err, &pgError) && pgError.ConstraintName == "account_email_lower_idx":
+		statusCode = response.ResponseErrorEmailTaken
+
+	// the unique index on the lowercase username
+	case errors.As(err, &pgError) && pgError.Code == "23505":
+		statusCode = response.ResponseErrorUsernameTaken
+
+	case err != nil:
+		statusCode = response.ResponseErrorDatabase
+
+		fmt.Printf("Error registering account [%s]: %s\n", registerRequest.Username, err.Error())
+	}
+
+	if statusCode != response.ResponseErrorSuccess {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(statusCode),
+		)
+
+		return
+	}
+
+	fmt.Printf("User [%s] with ID %d registered\n", registerRequest.Username, account)
+
+	writeAuditEvent(gc, account, AuditEventRegistered, nil)
+
+	// the account exists either way, the verification can be sent again by setting the email
+	if _, mailEnabled := utils.GetMailer(); mailEnabled && registerRequest.Email != "" {
+		sendEmailVerification(gc.Request.Context(), account, registerRequest.Username, registerRequest.Email)
+	}
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		response.IDResponse{
+			DefaultResponse: response.DefaultResponse{
+				Status: response.ResponseErrorSuccess,
+			},
+			ID: account,
+		},
+	)
+}
+
+// returns why the username can not be registered, nil if it can
+func checkUsername(username string) error {
+
+	var (
+		lowerUsername = strings.ToLower(username)
+	)
+
+	if !usernameRegex.MatchString(username) {
+		return errors.New("username has to be 3 to 32 letters, digits, underscores or hyphens and start with a letter")
+	}
+
+	for _, reserved := range reservedUsernames {
+		if lowerUsername == reserved {
+			return errors.New("username is reserved")
+		}
+	}
+
+	for _, blocked := range utils.GetBlockedUsernames() {
+		if strings.Contains(lowerUsername, blocked) {
+			return errors.New("username is not allowed")
+		}
+	}
+
+	return nil
+}
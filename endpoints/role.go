@@ -0,0 +1,99 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+
+	"PSF-LoginAPI/response"
+	"PSF-LoginAPI/utils"
+)
+
+// returns the roles of the account and the permissions they grant,
+// including the permissions for modes the account was invited to
+func getAccountRoles(account int64) (statusCode int, roles []string, permissions []string) {
+
+	var (
+		err error
+
+		rows pgx.Rows
+	)
+
+	rows, err = utils.GetPostgrePool().Query(
+		context.Background(),
+		`SELECT "role" FROM "account_role" WHERE "account_id" = $1 ORDER BY "role"`,
+		account,
+	)
+	if err == nil {
+		roles, err = pgx.CollectRows(rows, pgx.RowTo[string])
+	}
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		statusCode = response.ResponseErrorDatabase
+
+		fmt.Printf("Error getting roles of account %d from DB: %s\n", account, err.Error())
+
+		return
+	}
+
+	if len(roles) == 0 {
+		roles = []string{utils.RolePlayer}
+	}
+
+	rows, err = utils.GetPostgrePool().Query(
+		context.Background(),
+		`
+SELECT "permission" FROM "role_permission" WHERE "role" = ANY($1)
+UNION
+SELECT 'mode.grant.' || "mode" FROM "account_mode_grant" WHERE "account_id" = $2
+ORDER BY 1
+`,
+		roles,
+		account,
+	)
+	if err == nil {
+		permissions, err = pgx.CollectRows(rows, pgx.RowTo[string])
+	}
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		statusCode = response.ResponseErrorDatabase
+
+		fmt.Printf("Error getting permissions of account %d from DB: %s\n", account, err.Error())
+
+		return
+	}
+
+	if permissions == nil {
+		permissions = []string{}
+	}
+
+	return
+}
+
+// LoadRequestPermissions sets the current permissions of the token account on the request
+// instead of the ones the token was issued with, tokens can be refreshed long after a role was removed
+func LoadRequestPermissions(gc *gin.Context) (statusCode int) {
+
+	var (
+		ok bool
+
+		account int64
+
+		permissions []string
+	)
+
+	account, ok = getClaimsAccount(gc)
+	if !ok {
+		return response.ResponseErrorPermissionDenied
+	}
+
+	statusCode, _, permissions = getAccountRoles(account)
+	if statusCode != response.ResponseErrorSuccess {
+		return
+	}
+
+	gc.Set("permissions", permissions)
+
+	return
+}
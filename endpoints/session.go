@@ -0,0 +1,119 @@
+package endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"PSF-LoginAPI/cache"
+	"PSF-LoginAPI/response"
+	"PSF-LoginAPI/utils"
+)
+
+// session generation per account, tokens of older generations are revoked
+var sessionCache = cache.New[int64, int64]("session", utils.GetCacheTTL())
+
+// CheckSession returns ResponseErrorLauncherTokenExpired if the sessions of the token account were revoked after it was issued
+func CheckSession(claims jwt.MapClaims) (statusCode int) {
+
+	var (
+		account    int64
+		session    int64
+		generation int64
+	)
+
+	number, ok := claims["account"].(json.Number)
+	if !ok {
+		return
+	}
+
+	account, _ = number.Int64()
+
+	// tokens issued before sessions were tracked are of the first generation
+	if number, ok = claims["session"].(json.Number); ok {
+		session, _ = number.Int64()
+	}
+
+	statusCode, generation = getSessionGeneration(account)
+	if statusCode != response.ResponseErrorSuccess {
+		return
+	}
+
+	if session != generation {
+		statusCode = response.ResponseErrorLauncherTokenExpired
+
+		fmt.Printf("Account ID [%d] used token of revoked session %d\n", account, session)
+	}
+
+	return
+}
+
+func getSessionGeneration(account int64) (statusCode int, generation int64) {
+
+	generation = sessionCache.Get(
+		account,
+		func(account int64) (int64, bool) {
+			statusCode, generation = loadSessionGeneration(account)
+			return generation, statusCode == response.ResponseErrorSuccess
+		},
+	)
+
+	return
+}
+
+func loadSessionGeneration(account int64) (statusCode int, generation int64) {
+
+	var (
+		err error
+	)
+
+	err = utils.GetPostgrePool().QueryRow(
+		context.Background(),
+		`SELECT "session_generation" FROM "account" WHERE "id" = $1`,
+		account,
+	).Scan(&generation)
+	if err != nil {
+		statusCode = response.ResponseErrorDatabase
+
+		fmt.Printf("Error getting session of account %d from DB: %s\n", account, err.Error())
+
+		return
+	}
+
+	return
+}
+
+// replaces the password hash of the account and revokes all its sessions and its game token,
+// returns the new session generation
+func setAccountPassword(account int64, hash string) (statusCode int, generation int64) {
+
+	var (
+		err error
+	)
+
+	err = utils.GetPostgrePool().QueryRow(
+		context.Background(),
+		`
+UPDATE "account"
+SET "password" = $2, "session_generation" = "session_generation" + 1, "token" = NULL
+WHERE "id" = $1
+RETURNING "session_generation"
+`,
+		account,
+		hash,
+	).Scan(&generation)
+	if err != nil {
+		statusCode = response.ResponseErrorDatabase
+
+		fmt.Printf("Error setting password of account %d: %s\n", account, err.Error())
+
+		return
+	}
+
+	// do not wait for the notification to drop the old generation
+	cache.InvalidateTopic("session")
+
+	return
+}
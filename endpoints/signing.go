@@ -0,0 +1,36 @@
+package endpoints
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"PSF-LoginAPI/response"
+	"PSF-LoginAPI/utils"
+)
+
+// SigningKey publishes the public key responses are signed with, so launchers can pin it
+func SigningKey(gc *gin.Context) {
+
+	var (
+		privateKey, enabled = utils.GetResponseSigningKey()
+	)
+
+	if !enabled {
+		gc.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		response.SigningKeyResponse{
+			DefaultResponse: response.DefaultResponse{
+				Status: response.ResponseErrorSuccess,
+			},
+			Algorithm: "Ed25519",
+			PublicKey: base64.StdEncoding.EncodeToString(privateKey.Public().(ed25519.PublicKey)),
+		},
+	)
+}
@@ -0,0 +1,698 @@
+package endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"PSF-LoginAPI/response"
+	"PSF-LoginAPI/totp"
+	"PSF-LoginAPI/utils"
+)
+
+// how long a launcher has to complete a login with the second factor
+const twoFactorChallengeLifetime = 5 * time.Minute
+
+const (
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10
+)
+
+// codes accepted one step before and after the current one for clock drift
+const totpSkew = 1
+
+// code attempts per account, a code has 6 digits only
+var twoFactorLimiter = utils.NewRateLimiter(5, twoFactorChallengeLifetime)
+
+type TwoFactorLoginRequest struct {
+	Challenge string `json:"challenge" binding:"required"`
+	// code of the authenticator or a recovery code
+	Code string `json:"code" binding:"required"`
+}
+
+type EnableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+type ConfirmTwoFactorRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+	// code of the authenticator or a recovery code
+	Code string `json:"code" binding:"required"`
+}
+
+func (state *loginState) claims(purpose string) *jwt.MapClaims {
+	return &jwt.MapClaims{
+		"purpose":      purpose,
+		"account":      state.Account,
+		"session":      state.Session,
+		"username":     state.Username,
+		"mode":         state.Mode,
+		"launcher":     state.Launcher,
+		"platform":     state.Platform,
+		"launcherHash": state.LauncherHash,
+		"device":       state.Device,
+	}
+}
+
+// returns the login state of a challenge token, false if the claims are not the ones of a challenge
+func loginStateFromClaims(claims jwt.MapClaims) (state *loginState, ok bool) {
+
+	var (
+		err error
+
+		account, session, mode json.Number
+	)
+
+	state = &loginState{}
+
+	account, ok = claims["account"].(json.Number)
+	if ok {
+		session, ok = claims["session"].(json.Number)
+	}
+	if ok {
+		mode, ok = claims["mode"].(json.Number)
+	}
+	if ok {
+		state.Username, ok = claims["username"].(string)
+	}
+	if ok {
+		state.Launcher, ok = claims["launcher"].(string)
+	}
+	if ok {
+		state.Platform, ok = claims["platform"].(string)
+	}
+	if ok {
+		state.LauncherHash, ok = claims["launcherHash"].(string)
+	}
+	if ok {
+		state.Device, ok = claims["device"].(string)
+	}
+	if !ok {
+		return nil, false
+	}
+
+	state.Account, err = account.Int64()
+	if err == nil {
+		state.Session, err = session.Int64()
+	}
+	if err == nil {
+		state.Mode, err = mode.Int64()
+	}
+
+	return state, err == nil
+}
+
+// responds with a challenge the launcher completes the login with
+func issueTwoFactorChallenge(gc *gin.Context, state *loginState) {
+	issueLoginChallenge(gc, state, utils.TokenPurposeTwoFactor, response.ResponseErrorTwoFactorRequired, twoFactorChallengeLifetime)
+}
+
+// responds with a challenge token of the purpose that carries the login state
+func issueLoginChallenge(gc *gin.Context, state *loginState, purpose string, statusCode int, lifetime time.Duration) {
+
+	var (
+		err error
+
+		challenge string
+	)
+
+	challenge, err = utils.GenerateTokenWithLifetime(state.claims(purpose), lifetime)
+	if err != nil {
+
+		fmt.Printf("Token singing failed: %s\n", err.Error())
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(response.ResponseErrorInternalTokenCreationFailed),
+		)
+
+		return
+	}
+
+	fmt.Printf("User [%s] with ID %d has to complete login with %s challenge\n", state.Username, state.Account, purpose)
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		response.TwoFactorChallengeResponse{
+			DefaultResponse: response.DefaultResponse{
+				Status: statusCode,
+			},
+			Challenge: challenge,
+		},
+	)
+}
+
+// returns the login state of the challenge token of the purpose,
+// responds and returns false if it is invalid or expired
+func parseLoginChallenge(gc *gin.Context, challenge string, purpose string) (state *loginState, ok bool) {
+
+	var (
+		err error
+
+		decodedToken *jwt.Token
+		claims       *jwt.MapClaims
+	)
+
+	decodedToken, claims, err = utils.ParseToken(challenge)
+	if errors.Is(err, jwt.ErrTokenExpired) {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponseWithText(
+				response.ResponseErrorLauncherTokenExpired,
+				"login expired",
+			),
+		)
+
+		return nil, false
+	}
+	if err == nil && decodedToken.Valid && (*claims)["purpose"] == purpose {
+		state, ok = loginStateFromClaims(*claims)
+	}
+	if !ok {
+
+		fmt.Printf("Login with invalid %s challenge\n", purpose)
+
+		gc.AbortWithStatus(http.StatusBadRequest)
+		return nil, false
+	}
+
+	return state, true
+}
+
+// uses up the challenge and responds with the login token, challenges can only be used once
+func completeChallengeLogin(gc *gin.Context, state *loginState, challenge string, lifetime time.Duration) bool {
+
+	if !useChallenge(challenge, lifetime) {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponseWithText(
+				response.ResponseErrorLauncherTokenExpired,
+				"login expired",
+			),
+		)
+
+		return false
+	}
+
+	// the device is trusted from now on
+	state.Confirmed = true
+
+	completeLogin(gc, state)
+
+	return true
+}
+
+// LoginTwoFactor completes a login with the challenge of Login and the code of the authenticator or a recovery code
+func LoginTwoFactor(gc *gin.Context) {
+
+	var (
+		err error
+
+		ok       bool
+		recovery bool
+
+		statusCode int
+
+		state *loginState
+
+		loginRequest TwoFactorLoginRequest
+	)
+
+	err = gc.BindJSON(&loginRequest)
+	if err != nil {
+		fmt.Println("Could not parse request body as POST LoginTwoFactor")
+
+		return
+	}
+
+	state, ok = parseLoginChallenge(gc, loginRequest.Challenge, utils.TokenPurposeTwoFactor)
+	if !ok {
+		return
+	}
+
+	statusCode, recovery = verifySecondFactor(state.Account, loginRequest.Code)
+	if statusCode == response.ResponseErrorWrongTwoFactorCode {
+		writeAuditEvent(gc, state.Account, AuditEventTwoFactorFailed, nil)
+	}
+	if statusCode != response.ResponseErrorSuccess {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(statusCode),
+		)
+
+		return
+	}
+
+	if completeChallengeLogin(gc, state, loginRequest.Challenge, twoFactorChallengeLifetime) && recovery {
+		writeAuditEvent(gc, state.Account, AuditEventRecoveryCodeUsed, nil)
+	}
+}
+
+// checks the code of the authenticator or a recovery code of the account and uses it up,
+// recovery is true if a recovery code was used
+func verifySecondFactor(account int64, code string) (statusCode int, recovery bool) {
+
+	var (
+		err error
+
+		ok bool
+
+		step int64
+
+		secret string
+	)
+
+	if !twoFactorLimiter.Allow(strconv.FormatInt(account, 10)) {
+		statusCode = response.ResponseErrorRateLimited
+
+		fmt.Printf("Account ID [%d] second factor attempts rate limited\n", account)
+
+		return
+	}
+
+	// recovery codes are longer than authenticator codes
+	recovery = len(code) != totp.Digits
+
+	if recovery {
+
+		var tag pgconn.CommandTag
+
+		tag, err = utils.GetPostgrePool().Exec(
+			context.Background(),
+			`UPDATE "account_recovery_code" SET "used_at" = NOW() WHERE "account_id" = $1 AND "code_hash" = $2 AND "used_at" IS NULL`,
+			account,
+			hashAccountCode(code),
+		)
+		ok = err == nil && tag.RowsAffected() == 1
+
+	} else {
+
+		err = utils.GetPostgrePool().QueryRow(
+			context.Background(),
+			`SELECT "secret" FROM "account_totp" WHERE "account_id" = $1 AND "enabled_at" IS NOT NULL`,
+			account,
+		).Scan(&secret)
+		if err == nil {
+			step, ok, err = totp.Validate(secret, code, time.Now(), totpSkew)
+		}
+
+		// codes can only be used once
+		if err == nil && ok {
+
+			var tag pgconn.CommandTag
+
+			tag, err = utils.GetPostgrePool().Exec(
+				context.Background(),
+				`UPDATE "account_totp" SET "last_used_step" = $2 WHERE "account_id" = $1 AND "last_used_step" < $2`,
+				account,
+				step,
+			)
+			ok = err == nil && tag.RowsAffected() == 1
+		}
+	}
+
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		statusCode = response.ResponseErrorTwoFactorNotEnabled
+
+	case err != nil:
+		statusCode = response.ResponseErrorDatabase
+
+		fmt.Printf("Error checking second factor of account %d: %s\n", account, err.Error())
+
+	case !ok:
+		statusCode = response.ResponseErrorWrongTwoFactorCode
+
+		fmt.Printf("Account ID [%d] used wrong second factor code\n", account)
+	}
+
+	return
+}
+
+// EnableTwoFactor starts the enrollment of an authenticator, it is enabled once a code is confirmed
+func EnableTwoFactor(gc *gin.Context) {
+
+	var (
+		err error
+
+		ok bool
+
+		statusCode int
+
+		accountID int64
+
+		secret string
+
+		account *Account
+
+		enableRequest EnableTwoFactorRequest
+	)
+
+	err = gc.BindJSON(&enableRequest)
+	if err != nil {
+		fmt.Println("Could not parse request body as POST EnableTwoFactor")
+
+		return
+	}
+
+	accountID, ok = getClaimsAccount(gc)
+	if !ok {
+		gc.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	statusCode, account = loadAccount(`"id" = $1`, accountID)
+	if statusCode == response.ResponseErrorSuccess {
+		statusCode = checkAccountPassword(gc.Request.Context(), account, enableRequest.Password)
+	}
+	if statusCode == response.ResponseErrorSuccess && account.TwoFactor {
+		statusCode = response.ResponseErrorTwoFactorEnabled
+	}
+	if statusCode != response.ResponseErrorSuccess {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(statusCode),
+		)
+
+		return
+	}
+
+	secret, err = totp.GenerateSecret()
+	if err == nil {
+
+		// a new enrollment replaces one that was never confirmed
+		_, err = utils.GetPostgrePool().Exec(
+			context.Background(),
+			`
+INSERT INTO "account_totp" ("account_id", "secret")
+VALUES ($1, $2)
+ON CONFLICT ("account_id") DO UPDATE
+SET "secret" = EXCLUDED."secret", "created_at" = NOW(), "last_used_step" = 0
+WHERE "account_totp"."enabled_at" IS NULL
+`,
+			account.ID,
+			secret,
+		)
+	}
+	if err != nil {
+
+		fmt.Printf("Error creating TOTP secret of account %d: %s\n", account.ID, err.Error())
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(response.ResponseErrorDatabase),
+		)
+
+		return
+	}
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		response.TwoFactorEnrollmentResponse{
+			DefaultResponse: response.DefaultResponse{
+				Status: response.ResponseErrorSuccess,
+			},
+			URI:    totp.URI(utils.GetTOTPIssuer(), account.Username, secret),
+			Secret: secret,
+		},
+	)
+}
+
+// ConfirmTwoFactor enables the authenticator of the enrollment with one of its codes and returns the recovery codes
+func ConfirmTwoFactor(gc *gin.Context) {
+
+	var (
+		err error
+
+		ok bool
+
+		step int64
+
+		accountID int64
+
+		secret string
+
+		recoveryCodes []string
+
+		confirmRequest ConfirmTwoFactorRequest
+	)
+
+	err = gc.BindJSON(&confirmRequest)
+	if err != nil {
+		fmt.Println("Could not parse request body as POST ConfirmTwoFactor")
+
+		return
+	}
+
+	accountID, ok = getClaimsAccount(gc)
+	if !ok {
+		gc.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	if !twoFactorLimiter.Allow(strconv.FormatInt(accountID, 10)) {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(response.ResponseErrorRateLimited),
+		)
+
+		return
+	}
+
+	err = utils.GetPostgrePool().QueryRow(
+		context.Background(),
+		`SELECT "secret" FROM "account_totp" WHERE "account_id" = $1 AND "enabled_at" IS NULL`,
+		accountID,
+	).Scan(&secret)
+	if err == nil {
+		step, ok, err = totp.Validate(secret, confirmRequest.Code, time.Now(), totpSkew)
+	}
+
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(response.ResponseErrorTwoFactorNotEnabled),
+		)
+
+		return
+
+	case err != nil:
+
+		fmt.Printf("Error getting TOTP secret of account %d: %s\n", accountID, err.Error())
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(response.ResponseErrorDatabase),
+		)
+
+		return
+
+	case !ok:
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(response.ResponseErrorWrongTwoFactorCode),
+		)
+
+		return
+	}
+
+	err = pgx.BeginFunc(
+		context.Background(),
+		utils.GetPostgrePool(),
+		func(tx pgx.Tx) error {
+
+			tag, err := tx.Exec(
+				context.Background(),
+				`UPDATE "account_totp" SET "enabled_at" = NOW(), "last_used_step" = $2 WHERE "account_id" = $1 AND "enabled_at" IS NULL`,
+				accountID,
+				step,
+			)
+			if err != nil {
+				return err
+			}
+
+			// confirmed concurrently
+			if tag.RowsAffected() == 0 {
+				return pgx.ErrNoRows
+			}
+
+			recoveryCodes, err = createRecoveryCodes(tx, accountID)
+
+			return err
+		},
+	)
+	if err != nil {
+
+		statusCode := response.ResponseErrorDatabase
+		if errors.Is(err, pgx.ErrNoRows) {
+			statusCode = response.ResponseErrorTwoFactorEnabled
+		} else {
+			fmt.Printf("Error enabling TOTP of account %d: %s\n", accountID, err.Error())
+		}
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(statusCode),
+		)
+
+		return
+	}
+
+	fmt.Printf("Account ID [%d] enabled two-factor authentication\n", accountID)
+
+	writeAuditEvent(gc, accountID, AuditEventTwoFactorEnabled, nil)
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		response.RecoveryCodesResponse{
+			DefaultResponse: response.DefaultResponse{
+				Status: response.ResponseErrorSuccess,
+			},
+			RecoveryCodes: recoveryCodes,
+		},
+	)
+}
+
+// DisableTwoFactor removes the authenticator and recovery codes of the account,
+// it requires the password and a code
+func DisableTwoFactor(gc *gin.Context) {
+
+	var (
+		err error
+
+		ok bool
+
+		statusCode int
+
+		accountID int64
+
+		account *Account
+
+		disableRequest DisableTwoFactorRequest
+	)
+
+	err = gc.BindJSON(&disableRequest)
+	if err != nil {
+		fmt.Println("Could not parse request body as DELETE DisableTwoFactor")
+
+		return
+	}
+
+	accountID, ok = getClaimsAccount(gc)
+	if !ok {
+		gc.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	statusCode, account = loadAccount(`"id" = $1`, accountID)
+	if statusCode == response.ResponseErrorSuccess {
+		statusCode = checkAccountPassword(gc.Request.Context(), account, disableRequest.Password)
+	}
+	if statusCode == response.ResponseErrorSuccess && !account.TwoFactor {
+		statusCode = response.ResponseErrorTwoFactorNotEnabled
+	}
+	if statusCode == response.ResponseErrorSuccess {
+		statusCode, _ = verifySecondFactor(account.ID, disableRequest.Code)
+	}
+	if statusCode != response.ResponseErrorSuccess {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(statusCode),
+		)
+
+		return
+	}
+
+	err = pgx.BeginFunc(
+		context.Background(),
+		utils.GetPostgrePool(),
+		func(tx pgx.Tx) error {
+
+			_, err := tx.Exec(context.Background(), `DELETE FROM "account_recovery_code" WHERE "account_id" = $1`, account.ID)
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.Exec(context.Background(), `DELETE FROM "account_totp" WHERE "account_id" = $1`, account.ID)
+
+			return err
+		},
+	)
+	if err != nil {
+
+		fmt.Printf("Error disabling TOTP of account %d: %s\n", account.ID, err.Error())
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(response.ResponseErrorDatabase),
+		)
+
+		return
+	}
+
+	fmt.Printf("User [%s] with ID %d disabled two-factor authentication\n", account.Username, account.ID)
+
+	writeAuditEvent(gc, account.ID, AuditEventTwoFactorDisabled, nil)
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		response.DefaultResponse{
+			Status: response.ResponseErrorSuccess,
+		},
+	)
+}
+
+// replaces the recovery codes of the account, only their hashes are stored
+func createRecoveryCodes(tx pgx.Tx, account int64) (recoveryCodes []string, err error) {
+
+	var (
+		batch pgx.Batch
+	)
+
+	batch.Queue(`DELETE FROM "account_recovery_code" WHERE "account_id" = $1`, account)
+
+	recoveryCodes = make([]string, recoveryCodeCount)
+
+	for i := range recoveryCodes {
+
+		recoveryCodes[i], err = utils.RandomCode(recoveryCodeLength)
+		if err != nil {
+			return nil, err
+		}
+
+		batch.Queue(
+			`INSERT INTO "account_recovery_code" ("account_id", "code_hash") VALUES ($1, $2)`,
+			account,
+			hashAccountCode(recoveryCodes[i]),
+		)
+	}
+
+	err = tx.SendBatch(context.Background(), &batch).Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return recoveryCodes, nil
+}
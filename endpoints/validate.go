@@ -1,10 +1,9 @@
 package endpoints
 
 import (
-	"context"
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"hash"
 	"net/http"
@@ -12,55 +11,104 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/jackc/pgx/v5"
+	"golang.org/x/crypto/blake2b"
 
 	"PSF-LoginAPI/response"
 	"PSF-LoginAPI/utils"
 )
 
-const fileHashQuery = `
-SELECT %s
-FROM filehash
-WHERE
-		"mode" = $1
-	OR (
-			"mode" = 0
-		AND
-			NOT EXISTS (
-				SELECT 1
-				FROM filehash AS selectedMode
-				WHERE selectedMode.mode = $1
-				AND selectedMode.file = filehash.file
-			)
-	)
-ORDER BY "file";
-`
+// Aggregate hash algorithms
+const (
+	HashAlgorithmSHA1       = "sha1"
+	HashAlgorithmSHA256     = "sha256"
+	HashAlgorithmBLAKE2b256 = "blake2b-256"
+)
+
+// supported aggregate hash algorithms in order of preference
+var hashAlgorithms = []string{
+	HashAlgorithmSHA256,
+	HashAlgorithmBLAKE2b256,
+	HashAlgorithmSHA1,
+}
+
+var hashAlgorithmConstructors = map[string]func() hash.Hash{
+	HashAlgorithmSHA1:   sha1.New,
+	HashAlgorithmSHA256: sha256.New,
+	HashAlgorithmBLAKE2b256: func() hash.Hash {
+		// only fails for keys longer than 64 bytes
+		hasher, _ := blake2b.New256(nil)
+		return hasher
+	},
+}
 
 type ValidateRequest struct {
 	Launcher string `json:"launcher" binding:"required"`
-	Files    string `json:"files" binding:"required"`
+	// aggregate hash algorithm, older launchers do not send it and use SHA1
+	Algorithm string `json:"algorithm"`
+	// aggregate hash of all required files, sent by older launchers
+	Files string `json:"files"`
+	// file name to file hash of every file matching the manifest rules
+	Hashes map[string]string `json:"hashes"`
 }
 
 func ValidateGet(gc *gin.Context) {
 
 	var (
+		statusCode int
+
+		etag string
+
 		verifyFileNames []string
+		algorithms      []string
+
+		manifest *Manifest
 
 		validateResponse *response.ValidateResponse
 
 		pClaims, _ = gc.Get("claims")
 		claims     = pClaims.(jwt.MapClaims)
 
-		mode, _ = (claims["mode"]).(json.Number).Int64()
+		mode, _         = (claims["mode"]).(json.Number).Int64()
+		launcherVersion = claims["launcher"]
 	)
 
-	verifyFileNames = getFileForMode(mode, `"file"`)
+	statusCode, manifest = getManifestForMode(mode)
+	if statusCode != response.ResponseErrorSuccess {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(statusCode),
+		)
+
+		return
+	}
+
+	algorithms = getHashAlgorithmsForLauncher(launcherVersion)
+
+	// the response only depends on the manifest and the allowed algorithms
+	etag = utils.CreateETag(manifest.ETag, strings.Join(algorithms, ","))
+
+	gc.Header("ETag", etag)
+	gc.Header("Cache-Control", "private, no-cache")
+
+	if utils.MatchesETag(gc.GetHeader("If-None-Match"), etag) {
+		gc.Status(http.StatusNotModified)
+		return
+	}
+
+	for _, rule := range manifest.Rules {
+		if rule.isLegacy() {
+			verifyFileNames = append(verifyFileNames, rule.File)
+		}
+	}
 
 	validateResponse = &response.ValidateResponse{
 		DefaultResponse: response.DefaultResponse{
 			Status: response.ResponseErrorSuccess,
 		},
-		Files: verifyFileNames,
+		Files:      verifyFileNames,
+		Rules:      createFileRules(manifest.Rules),
+		Algorithms: algorithms,
 	}
 
 	gc.IndentedJSON(
@@ -74,19 +122,23 @@ func ValidatePost(gc *gin.Context) {
 	var (
 		err error
 
-		token        string
-		allFilesHash string
+		statusCode int
 
-		verifyFileHashes []string
+		token string
 
-		hasher hash.Hash
+		failedFiles []string
+
+		manifest *Manifest
 
 		validationRequest ValidateRequest
+		verifiedClaims    jwt.MapClaims
 
 		pClaims, _ = gc.Get("claims")
 		claims     = pClaims.(jwt.MapClaims)
 
-		mode, _ = (claims["mode"]).(json.Number).Int64()
+		account, _      = (claims["account"]).(json.Number).Int64()
+		mode, _         = (claims["mode"]).(json.Number).Int64()
+		launcherVersion = claims["launcher"]
 	)
 
 	// get client response body
@@ -97,22 +149,54 @@ func ValidatePost(gc *gin.Context) {
 		return
 	}
 
-	// get file hashes for mode
-	verifyFileHashes = getFileForMode(mode, `"hash"`)
+	// older launchers do not send an algorithm
+	if validationRequest.Algorithm == "" {
+		validationRequest.Algorithm = HashAlgorithmSHA1
+	}
+
+	if !isHashAlgorithmAllowed(launcherVersion, validationRequest.Algorithm) {
+
+		fmt.Printf(
+			"Account ID [%s] with launcher version [%v] requested unsupported hash algorithm [%s]\n",
+			claims["account"],
+			launcherVersion,
+			validationRequest.Algorithm,
+		)
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(response.ResponseErrorUnsupportedHashAlgorithm),
+		)
+
+		return
+	}
+
+	// get manifest for mode
+	statusCode, manifest = getManifestForMode(mode)
+	if statusCode != response.ResponseErrorSuccess {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(statusCode),
+		)
 
-	hasher = sha1.New()
-	for _, fileHash := range verifyFileHashes {
-		hasher.Write([]byte(fileHash))
+		return
 	}
 
-	allFilesHash = fmt.Sprintf("%x", hasher.Sum(nil))
+	// launchers that report single file hashes are validated against all rules
+	if validationRequest.Hashes != nil {
+		failedFiles = evaluateManifest(manifest.Rules, validationRequest.Hashes)
+	} else if !validateAggregateHash(manifest.Rules, validationRequest.Algorithm, validationRequest.Files) {
+		failedFiles = []string{"aggregate"}
+	}
 
-	if strings.Compare(allFilesHash, validationRequest.Files) != 0 {
+	if len(failedFiles) > 0 {
 
 		fmt.Printf(
-			"File verification failed for account ID [%s] and mode [%s]\n",
+			"File verification failed for account ID [%s] and mode [%s]: %s\n",
 			claims["account"],
 			claims["mode"],
+			strings.Join(failedFiles, ", "),
 		)
 
 		gc.IndentedJSON(
@@ -123,14 +207,16 @@ func ValidatePost(gc *gin.Context) {
 		return
 	}
 
+	// the verified token is a fresh one, bans since the login apply
+	if !checkBan(gc, account) {
+		return
+	}
+
 	// generate token
-	token, err = utils.GenerateToken(
-		&jwt.MapClaims{
-			"account":  claims["account"],
-			"mode":     claims["mode"],
-			"verified": true,
-		},
-	)
+	verifiedClaims = getCarriedClaims(claims)
+	verifiedClaims["verified"] = true
+
+	token, err = utils.GenerateToken(&verifiedClaims)
 	if err != nil {
 
 		fmt.Printf("Token singing failed: %s\n", err.Error())
@@ -149,34 +235,56 @@ func ValidatePost(gc *gin.Context) {
 			DefaultResponse: response.DefaultResponse{
 				Status: response.ResponseErrorSuccess,
 			},
-			Token: token,
+			Token:           token,
+			UpdateAvailable: gc.GetBool("updateAvailable"),
 		},
 	)
 
 	return
 }
 
-func getFileForMode(mode int64, column string) (fileNames []string) {
+// returns the aggregate hash algorithms the launcher version may use
+func getHashAlgorithmsForLauncher(launcherVersion interface{}) (algorithms []string) {
 
-	var (
-		err error
+	for _, algorithm := range hashAlgorithms {
+		if isHashAlgorithmAllowed(launcherVersion, algorithm) {
+			algorithms = append(algorithms, algorithm)
+		}
+	}
 
-		rows pgx.Rows
-	)
+	return
+}
 
-	rows, err = utils.GetPostgrePool().Query(
-		context.Background(),
-		fmt.Sprintf(fileHashQuery, column),
-		mode,
-	)
-	if err != nil {
-		return
+func isHashAlgorithmAllowed(launcherVersion interface{}, algorithm string) bool {
+
+	_, exists := hashAlgorithmConstructors[algorithm]
+	if !exists {
+		return false
 	}
 
-	fileNames, err = pgx.CollectRows(rows, pgx.RowTo[string])
-	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
-		return
+	if algorithm != HashAlgorithmSHA1 {
+		return true
 	}
 
-	return
+	// SHA1 is only accepted from launchers that are configured to still use it
+	version, _ := launcherVersion.(string)
+
+	return getLauncherAllowsSHA1(version)
+}
+
+// validates the aggregate hash launchers build over all legacy rule hashes
+func validateAggregateHash(rules []ManifestRule, algorithm string, allFilesHash string) bool {
+
+	var (
+		hasher hash.Hash
+	)
+
+	hasher = hashAlgorithmConstructors[algorithm]()
+	for _, rule := range rules {
+		if rule.isLegacy() {
+			hasher.Write([]byte(rule.Hash))
+		}
+	}
+
+	return strings.EqualFold(fmt.Sprintf("%x", hasher.Sum(nil)), allFilesHash)
 }
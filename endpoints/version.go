@@ -1,33 +1,117 @@
 package endpoints
 
 import (
-	"context"
-	"errors"
 	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/jackc/pgx/v5"
 
 	"PSF-LoginAPI/response"
 	"PSF-LoginAPI/utils"
 )
 
-type LauncherInfo struct {
-	Version    string    `db:"version"`
-	ReleasedAt time.Time `db:"released_at"`
+func Version(gc *gin.Context) {
+
+	var (
+		channel string
+
+		launcher *Launcher
+	)
+
+	channel, launcher = getLauncherForVersionRequest(gc)
+	if launcher == nil {
+		return
+	}
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		response.VersionResponse{
+			DefaultResponse: response.DefaultResponse{
+				Status: response.ResponseErrorSuccess,
+			},
+			ReleaseDate:   launcher.ReleasedAt.Unix(),
+			VersionString: launcher.Version,
+			Channel:       channel,
+		},
+	)
 }
 
-func Version(gc *gin.Context) {
+// VersionV2 returns the newest launcher release including everything needed to download and verify it.
+// The download fields are the ones of the platform and arch query parameters.
+func VersionV2(gc *gin.Context) {
+
+	var (
+		channel string
+
+		launcher *Launcher
+		artifact *LauncherArtifact
+
+		artifacts = []response.LauncherArtifact{}
+
+		platform = gc.DefaultQuery("platform", DefaultLauncherPlatform)
+		arch     = gc.DefaultQuery("arch", DefaultLauncherArch)
+	)
+
+	channel, launcher = getLauncherForVersionRequest(gc)
+	if launcher == nil {
+		return
+	}
+
+	for _, launcherArtifact := range launcher.Artifacts {
+		artifacts = append(artifacts, createLauncherArtifact(&launcherArtifact))
+	}
+
+	// release has no build for the platform
+	artifact = launcher.getArtifact(platform, arch)
+	if artifact == nil {
+		artifact = &LauncherArtifact{
+			Platform: platform,
+			Arch:     arch,
+			Mirrors:  []string{},
+		}
+	}
+
+	gc.IndentedJSON(
+		http.StatusOK,
+		response.VersionResponseV2{
+			VersionResponse: response.VersionResponse{
+				DefaultResponse: response.DefaultResponse{
+					Status: response.ResponseErrorSuccess,
+				},
+				ReleaseDate:   launcher.ReleasedAt.Unix(),
+				VersionString: launcher.Version,
+				Channel:       channel,
+			},
+			LauncherArtifact: createLauncherArtifact(artifact),
+			Changelog:        launcher.Changelog,
+			Mandatory:        launcher.Mandatory,
+			MinimumOS:        launcher.MinimumOS,
+			Artifacts:        artifacts,
+		},
+	)
+}
+
+func createLauncherArtifact(artifact *LauncherArtifact) response.LauncherArtifact {
+	return response.LauncherArtifact{
+		Platform:    artifact.Platform,
+		Arch:        artifact.Arch,
+		DownloadURL: artifact.DownloadURL,
+		Mirrors:     artifact.Mirrors,
+		Size:        artifact.Size,
+		SHA256:      artifact.SHA256,
+		Signature:   artifact.Signature,
+	}
+}
+
+// returns the newest launcher on the caller's channel, nil if the response was already written
+func getLauncherForVersionRequest(gc *gin.Context) (channel string, launcher *Launcher) {
 
 	var (
 		statusCode int
 
 		matches []string
 
-		launcherInfo *LauncherInfo
-
 		request = gc.Request
 	)
 
@@ -39,7 +123,9 @@ func Version(gc *gin.Context) {
 		return
 	}
 
-	statusCode, launcherInfo = getLastestLauncherVersion()
+	channel = ResolveLauncherChannel(gc)
+
+	statusCode, launcher = getLastestLauncherVersion(channel)
 	if statusCode != response.ResponseErrorSuccess {
 
 		gc.IndentedJSON(
@@ -47,58 +133,55 @@ func Version(gc *gin.Context) {
 			response.CreateErrorResponse(statusCode),
 		)
 
-		return
+		return channel, nil
 	}
 
-	gc.IndentedJSON(
-		http.StatusOK,
-		response.VersionResponse{
-			DefaultResponse: response.DefaultResponse{
-				Status: response.ResponseErrorSuccess,
-			},
-			ReleaseDate:   launcherInfo.ReleasedAt.Unix(),
-			VersionString: launcherInfo.Version,
-		},
-	)
+	return
 }
 
-func getLastestLauncherVersion() (statusCode int, launcherInfo *LauncherInfo) {
+func getLastestLauncherVersion(channel string) (statusCode int, launcher *Launcher) {
 
 	var (
 		err error
 
-		rows pgx.Rows
-	)
+		launchers []Launcher
 
-	rows, err = utils.GetPostgrePool().Query(
-		context.Background(),
-		`SELECT "version", "released_at" FROM "launcher" WHERE "active" = TRUE ORDER BY "released_at" DESC LIMIT 1`,
+		newestVersion   utils.LauncherVersion
+		launcherVersion utils.LauncherVersion
 	)
-	if err != nil {
-		statusCode = response.ResponseErrorDatabase
-
-		fmt.Printf("Error querying launcher information from DB: %s\n", err.Error())
 
+	statusCode, launchers = getLaunchers()
+	if statusCode != response.ResponseErrorSuccess {
 		return
 	}
 
-	launcherInfo, err = pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByName[LauncherInfo])
-	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
-		statusCode = response.ResponseErrorDatabase
+	// the channel might only have older builds than stable or none at all
+	for i := range launchers {
 
-		fmt.Printf("Error parsing launcher information from DB: %s\n", err.Error())
+		if !launchers[i].Active || !isLauncherOnChannel(&launchers[i], channel) {
+			continue
+		}
 
-		return
+		launcherVersion, err = utils.ParseLauncherVersion(launchers[i].Version)
+		if err != nil {
+			fmt.Printf("Launcher in DB has invalid version: %s\n", err.Error())
+			continue
+		}
+
+		if launcher == nil || launcherVersion.Compare(newestVersion) > 0 {
+			launcher = &launchers[i]
+			newestVersion = launcherVersion
+		}
 	}
 
-	// if there are no active launchers, send a fake one
-	if errors.Is(err, pgx.ErrNoRows) {
-		statusCode = response.ResponseErrorSuccess
+	if launcher != nil {
+		return
+	}
 
-		launcherInfo = &LauncherInfo{
-			Version:    "0.0.0.0",
-			ReleasedAt: time.Time{},
-		}
+	// if there are no active launchers on the channel or stable, send a fake one
+	launcher = &Launcher{
+		Version:    "0.0.0.0",
+		ReleasedAt: time.Time{},
 	}
 
 	return
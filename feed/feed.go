@@ -0,0 +1,174 @@
+// Package feed reads the items of RSS 2.0 and Atom feeds.
+package feed
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type Item struct {
+	// unique ID of the item within the feed
+	GUID        string
+	Title       string
+	Body        string
+	PublishedAt time.Time
+}
+
+type rssDocument struct {
+	Items []struct {
+		GUID        string `xml:"guid"`
+		Link        string `xml:"link"`
+		Title       string `xml:"title"`
+		Description string `xml:"description"`
+		PubDate     string `xml:"pubDate"`
+	} `xml:"channel>item"`
+}
+
+type atomDocument struct {
+	Entries []struct {
+		ID    string `xml:"id"`
+		Title string `xml:"title"`
+		Link  struct {
+			Href string `xml:"href,attr"`
+		} `xml:"link"`
+		Summary   string `xml:"summary"`
+		Content   string `xml:"content"`
+		Published string `xml:"published"`
+		Updated   string `xml:"updated"`
+	} `xml:"entry"`
+}
+
+// RSS dates are RFC 822 with a few common variations
+var rssDateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC822Z,
+	time.RFC822,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+}
+
+// Parse reads the items of an RSS 2.0 or Atom feed
+func Parse(reader io.Reader) (items []Item, err error) {
+
+	var (
+		data []byte
+
+		root struct {
+			XMLName xml.Name
+		}
+	)
+
+	data, err = io.ReadAll(reader)
+	if err != nil {
+		return
+	}
+
+	err = xml.Unmarshal(data, &root)
+	if err != nil {
+		return
+	}
+
+	switch root.XMLName.Local {
+	case "rss":
+		return parseRSS(data)
+	case "feed":
+		return parseAtom(data)
+	}
+
+	return nil, fmt.Errorf("unsupported feed format: %s", root.XMLName.Local)
+}
+
+func parseRSS(data []byte) (items []Item, err error) {
+
+	var (
+		document rssDocument
+	)
+
+	err = xml.Unmarshal(data, &document)
+	if err != nil {
+		return
+	}
+
+	for _, rssItem := range document.Items {
+
+		item := Item{
+			GUID:  firstNonEmpty(rssItem.GUID, rssItem.Link, rssItem.Title),
+			Title: strings.TrimSpace(rssItem.Title),
+			Body:  strings.TrimSpace(rssItem.Description),
+		}
+
+		item.PublishedAt, err = parseTime(rssItem.PubDate, rssDateLayouts)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, item)
+	}
+
+	return
+}
+
+func parseAtom(data []byte) (items []Item, err error) {
+
+	var (
+		document atomDocument
+	)
+
+	err = xml.Unmarshal(data, &document)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range document.Entries {
+
+		item := Item{
+			GUID:  firstNonEmpty(entry.ID, entry.Link.Href, entry.Title),
+			Title: strings.TrimSpace(entry.Title),
+			Body:  strings.TrimSpace(firstNonEmpty(entry.Content, entry.Summary)),
+		}
+
+		item.PublishedAt, err = parseTime(firstNonEmpty(entry.Published, entry.Updated), []string{time.RFC3339})
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, item)
+	}
+
+	return
+}
+
+// items without date are published right away
+func parseTime(value string, layouts []string) (time.Time, error) {
+
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Now(), nil
+	}
+
+	for _, layout := range layouts {
+
+		parsed, err := time.Parse(layout, value)
+		if err == nil {
+			return parsed, nil
+		}
+	}
+
+	return time.Time{}, errors.New("invalid feed date: " + value)
+}
+
+func firstNonEmpty(values ...string) string {
+
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return strings.TrimSpace(value)
+		}
+	}
+
+	return ""
+}
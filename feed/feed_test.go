@@ -0,0 +1,139 @@
+package feed
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const rssFeed = `<?xml version="1.0"?>
+<rss version="2.0">
+	<channel>
+		<title>PSForever</title>
+		<item>
+			<guid>news-1</guid>
+			<link>https://example.com/news/1</link>
+			<title> Server update </title>
+			<description>New continents</description>
+			<pubDate>Mon, 02 Jan 2006 15:04:05 +0000</pubDate>
+		</item>
+		<item>
+			<link>https://example.com/news/2</link>
+			<title>Single digit day</title>
+			<pubDate>Tue, 3 Jan 2006 15:04:05 GMT</pubDate>
+		</item>
+	</channel>
+</rss>`
+
+const atomFeed = `<?xml version="1.0"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+	<title>PSForever</title>
+	<entry>
+		<id>urn:news:1</id>
+		<title>Server update</title>
+		<summary>Short</summary>
+		<content>Full text</content>
+		<published>2006-01-02T15:04:05Z</published>
+		<updated>2006-01-05T15:04:05Z</updated>
+	</entry>
+	<entry>
+		<title>Only updated</title>
+		<link href="https://example.com/news/2"/>
+		<summary>Short</summary>
+		<updated>2006-01-05T15:04:05+01:00</updated>
+	</entry>
+</feed>`
+
+func TestParse(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		feed     string
+		expected []Item
+	}{
+		{
+			"rss",
+			rssFeed,
+			[]Item{
+				{"news-1", "Server update", "New continents", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+				{"https://example.com/news/2", "Single digit day", "", time.Date(2006, 1, 3, 15, 4, 5, 0, time.UTC)},
+			},
+		},
+		{
+			"atom",
+			atomFeed,
+			[]Item{
+				{"urn:news:1", "Server update", "Full text", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+				{"https://example.com/news/2", "Only updated", "Short", time.Date(2006, 1, 5, 14, 4, 5, 0, time.UTC)},
+			},
+		},
+		{
+			"empty rss",
+			`<rss version="2.0"><channel></channel></rss>`,
+			nil,
+		},
+	}
+
+	for _, test := range tests {
+
+		items, err := Parse(strings.NewReader(test.feed))
+		if err != nil {
+			t.Errorf("%s: Parse returned %v", test.name, err)
+			continue
+		}
+
+		if len(items) != len(test.expected) {
+			t.Errorf("%s: Parse returned %d items, expected %d", test.name, len(items), len(test.expected))
+			continue
+		}
+
+		for i, item := range items {
+
+			expected := test.expected[i]
+
+			if item.GUID != expected.GUID || item.Title != expected.Title || item.Body != expected.Body {
+				t.Errorf("%s: item %d = %+v, expected %+v", test.name, i, item, expected)
+			}
+
+			if !item.PublishedAt.Equal(expected.PublishedAt) {
+				t.Errorf("%s: item %d published at %s, expected %s", test.name, i, item.PublishedAt, expected.PublishedAt)
+			}
+		}
+	}
+}
+
+func TestParseWithoutDate(t *testing.T) {
+
+	var (
+		before = time.Now()
+	)
+
+	items, err := Parse(strings.NewReader(`<rss><channel><item><guid>1</guid></item></channel></rss>`))
+	if err != nil {
+		t.Fatalf("Parse returned %v", err)
+	}
+
+	if len(items) != 1 || items[0].PublishedAt.Before(before) {
+		t.Errorf("Parse returned %+v, expected one item published now", items)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+
+	tests := []struct {
+		name string
+		feed string
+	}{
+		{"not xml", "not a feed"},
+		{"unsupported format", `<html><body></body></html>`},
+		{"invalid rss date", `<rss><channel><item><guid>1</guid><pubDate>yesterday</pubDate></item></channel></rss>`},
+		{"invalid atom date", `<feed><entry><id>1</id><updated>2006-01-02</updated></entry></feed>`},
+	}
+
+	for _, test := range tests {
+
+		if _, err := Parse(strings.NewReader(test.feed)); err == nil {
+			t.Errorf("%s: Parse returned no error", test.name)
+		}
+	}
+}
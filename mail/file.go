@@ -0,0 +1,44 @@
+package mail
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileMailer writes every message as .eml file to the outbox directory instead of sending it,
+// for development and tests without a mail server
+type FileMailer struct {
+	Directory string
+	From      string
+}
+
+func (mailer *FileMailer) Send(_ context.Context, message *Message) error {
+
+	var (
+		suffix = make([]byte, 4)
+	)
+
+	_, err := rand.Read(suffix)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(mailer.Directory, 0o750)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(
+		filepath.Join(
+			mailer.Directory,
+			fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), hex.EncodeToString(suffix)),
+		),
+		format(mailer.From, message),
+		0o640,
+	)
+}
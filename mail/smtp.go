@@ -0,0 +1,49 @@
+package mail
+
+import (
+	"context"
+	"net"
+	"net/smtp"
+	"strconv"
+)
+
+// SMTPMailer sends messages through an SMTP server, with STARTTLS if the server supports it
+type SMTPMailer struct {
+	Host string
+	Port int
+	// no authentication if empty
+	Username string
+	Password string
+	From     string
+}
+
+func (mailer *SMTPMailer) Send(ctx context.Context, message *Message) error {
+
+	var (
+		auth smtp.Auth
+
+		done = make(chan error, 1)
+	)
+
+	if mailer.Username != "" {
+		auth = smtp.PlainAuth("", mailer.Username, mailer.Password, mailer.Host)
+	}
+
+	// net/smtp does not take a context
+	go func() {
+		done <- smtp.SendMail(
+			net.JoinHostPort(mailer.Host, strconv.Itoa(mailer.Port)),
+			auth,
+			mailer.From,
+			[]string{message.To},
+			format(mailer.From, message),
+		)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -3,15 +3,18 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"PSF-LoginAPI/cache"
 	"PSF-LoginAPI/endpoints"
 	"PSF-LoginAPI/response"
 	"PSF-LoginAPI/utils"
@@ -23,8 +26,12 @@ func main() {
 		err error
 
 		pool *pgxpool.Pool
+
+		importAnnouncements = flag.String("import-announcements", "", "import announcements from an RSS or Atom file and exit")
 	)
 
+	flag.Parse()
+
 	// connect to db, create pool
 	pool = utils.GetPostgrePool()
 
@@ -33,101 +40,302 @@ func main() {
 		log.Fatalf("Could not open database connection: %v", err.Error())
 	}
 
+	if *importAnnouncements != "" {
+		importAnnouncementFeed(*importAnnouncements)
+		return
+	}
+
+	// drop cached data when it changes in the db
+	go cache.Listen(context.Background(), pool)
+
+	// validate the configuration on startup instead of failing the first request that reads it
+	utils.GetMinimumLauncherVersion()
+	utils.GetLauncherAttestationMode()
+	utils.GetResponseSigningKey()
+	utils.GetPasswordHasher()
+	utils.GetPasswordPool()
+	utils.GetDummyPasswordHash()
+	utils.GetPasswordPolicy()
+	utils.GetRegistrationMode()
+	utils.GetRegistrationRateLimiter()
+	utils.GetBlockedUsernames()
+	utils.GetMailer()
+	utils.GetPasswordResetRateLimiter()
+	utils.GetDeviceVerificationMode()
+	utils.GetTrustedProxies()
+
 	// create router
 	router := gin.New()
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
-	_ = router.SetTrustedProxies(nil)
+
+	// client IPs are taken from the forwarded headers of trusted proxies only
+	err = router.SetTrustedProxies(utils.GetTrustedProxies())
+	if err != nil {
+		log.Fatalf("Could not set trusted proxies: %s", err.Error())
+	}
+
+	// behind a proxy every request has its address, per IP limits would apply to all players at once
+	if len(utils.GetTrustedProxies()) == 0 {
+		_, mailEnabled := utils.GetMailer()
+
+		if utils.GetRegistrationMode() != utils.RegistrationDisabled || mailEnabled {
+			fmt.Println("TRUSTED_PROXIES is not set, registration and password reset rate limits apply per proxy if the API is behind one")
+		}
+	}
 
 	// add live group
 	unauthenticated := router.Group("/psf/live")
 	{
 		// setup routes
-		unauthenticated.GET("/version", endpoints.Version)
-		unauthenticated.POST("/login", endpoints.Login)
+		unauthenticated.GET("/version", GetResponseSigningMiddleware(), GetOptionalAuthMiddleware(), endpoints.Version)
+		unauthenticated.GET("/challenge", endpoints.Challenge)
+		unauthenticated.GET("/modes", GetOptionalAuthMiddleware(), endpoints.Modes)
+		unauthenticated.GET("/maintenance", endpoints.Maintenance)
+		unauthenticated.GET("/announcements", GetOptionalAuthMiddleware(), endpoints.Announcements)
+		unauthenticated.GET("/signing-key", endpoints.SigningKey)
+		unauthenticated.POST("/login", GetLauncherVersionMiddleware(), endpoints.Login)
+		unauthenticated.POST("/login/2fa", GetLauncherVersionMiddleware(), endpoints.LoginTwoFactor)
+
+		if utils.GetDeviceVerificationMode() == utils.DeviceVerificationEmail {
+			unauthenticated.POST("/login/device", GetLauncherVersionMiddleware(), endpoints.LoginDevice)
+		}
+
+		if utils.GetRegistrationMode() != utils.RegistrationDisabled {
+			unauthenticated.POST("/register", GetRateLimitMiddleware(utils.GetRegistrationRateLimiter()), endpoints.Register)
+		}
+
+		if _, mailEnabled := utils.GetMailer(); mailEnabled {
+			unauthenticated.POST("/password-reset", GetRateLimitMiddleware(utils.GetPasswordResetRateLimiter()), endpoints.RequestPasswordReset)
+			unauthenticated.POST("/password-reset/confirm", GetRateLimitMiddleware(utils.GetPasswordResetRateLimiter()), endpoints.ConfirmPasswordReset)
+		}
+	}
+
+	// versioned endpoints for newer launchers
+	unauthenticatedV2 := router.Group("/psf/live/v2")
+	{
+		unauthenticatedV2.GET("/version", GetResponseSigningMiddleware(), GetOptionalAuthMiddleware(), endpoints.VersionV2)
 	}
 
 	authenticated := router.Group("/psf/live")
 	{
+		authenticated.Use(GetResponseSigningMiddleware())
 		authenticated.Use(GetAuthMiddleware())
+		authenticated.Use(GetLauncherVersionMiddleware())
 
 		authenticated.GET("/validate", endpoints.ValidateGet)
 		authenticated.POST("/validate", endpoints.ValidatePost)
 
 		authenticated.GET("/gametoken", endpoints.GameToken)
-	}
 
-	router.Run("localhost:9001")
-}
+		authenticated.POST("/account/password", endpoints.ChangePassword)
+		authenticated.POST("/invite", endpoints.RedeemInvite)
 
-func GetAuthMiddleware() gin.HandlerFunc {
+		authenticated.POST("/account/2fa", endpoints.EnableTwoFactor)
+		authenticated.POST("/account/2fa/confirm", endpoints.ConfirmTwoFactor)
+		authenticated.DELETE("/account/2fa", endpoints.DisableTwoFactor)
 
-	return func(gc *gin.Context) {
+		authenticated.GET("/account/devices", endpoints.GetDevices)
+		authenticated.DELETE("/account/devices/:id", endpoints.RevokeDevice)
 
-		var (
-			err error
+		if _, mailEnabled := utils.GetMailer(); mailEnabled {
+			authenticated.PUT("/account/email", endpoints.SetEmail)
+			authenticated.POST("/account/email/verify", endpoints.VerifyEmail)
+		}
+	}
 
-			token string
+	authenticatedV2 := router.Group("/psf/live/v2")
+	{
+		authenticatedV2.Use(GetResponseSigningMiddleware())
+		authenticatedV2.Use(GetAuthMiddleware())
+		authenticatedV2.Use(GetLauncherVersionMiddleware())
 
-			decodedToken *jwt.Token
-			claims       *jwt.MapClaims
+		authenticatedV2.GET("/gametoken", endpoints.GameTokenV2)
+	}
 
-			authHeader  = gc.Request.Header.Get("Authorization")
-			splitHeader = strings.Split(authHeader, "Bearer ")
-		)
+	admin := router.Group("/psf/admin")
+	{
+		admin.Use(GetAdminMiddleware())
 
-		if len(splitHeader) < 2 {
-			token = ""
-		} else {
-			token = splitHeader[1]
+		channels := admin.Group("", GetPermissionMiddleware(utils.PermissionAdminChannels))
+		{
+			channels.GET("/channels", endpoints.AdminGetLauncherChannels)
+			channels.PUT("/accounts/:account/channel", endpoints.AdminSetAccountLauncherChannel)
+			channels.DELETE("/accounts/:account/channel", endpoints.AdminDeleteAccountLauncherChannel)
 		}
 
-		// token is required from here
-		if token == "" {
-
-			fmt.Println("Authenticated API called without token")
+		roles := admin.Group("", GetPermissionMiddleware(utils.PermissionAdminRoles))
+		{
+			roles.GET("/roles", endpoints.AdminGetRoles)
+			roles.GET("/accounts/:account/roles", endpoints.AdminGetAccountRoles)
+			roles.PUT("/accounts/:account/roles/:role", endpoints.AdminAddAccountRole)
+			roles.DELETE("/accounts/:account/roles/:role", endpoints.AdminRemoveAccountRole)
+		}
 
-			gc.AbortWithStatus(http.StatusBadRequest)
-			return
+		maintenance := admin.Group("", GetPermissionMiddleware(utils.PermissionAdminMaintenance))
+		{
+			maintenance.POST("/maintenance", endpoints.AdminCreateMaintenanceWindow)
+			maintenance.DELETE("/maintenance/:id", endpoints.AdminDeleteMaintenanceWindow)
 		}
 
-		// decode token
-		decodedToken, claims, err = utils.ParseToken(token)
-		if err != nil && !errors.Is(err, jwt.ErrTokenExpired) {
+		announcements := admin.Group("", GetPermissionMiddleware(utils.PermissionAdminAnnouncements))
+		{
+			announcements.GET("/announcements", endpoints.AdminGetAnnouncements)
+			announcements.POST("/announcements", endpoints.AdminCreateAnnouncement)
+			announcements.POST("/announcements/import", endpoints.AdminImportAnnouncements)
+			announcements.PUT("/announcements/:id", endpoints.AdminUpdateAnnouncement)
+			announcements.DELETE("/announcements/:id", endpoints.AdminDeleteAnnouncement)
+		}
 
-			fmt.Printf("Authenticated API called with invalid token: %v\n", err.Error())
+		bans := admin.Group("", GetPermissionMiddleware(utils.PermissionAdminBans))
+		{
+			bans.GET("/bans", endpoints.AdminGetBans)
+			bans.POST("/bans", endpoints.AdminCreateBan)
+			bans.DELETE("/bans/:id", endpoints.AdminLiftBan)
+		}
 
-			gc.AbortWithStatus(http.StatusBadRequest)
-			return
+		invites := admin.Group("", GetPermissionMiddleware(utils.PermissionAdminInvites))
+		{
+			invites.GET("/invites", endpoints.AdminGetInviteCodes)
+			invites.POST("/invites", endpoints.AdminCreateInviteCodes)
+			invites.DELETE("/invites/:code", endpoints.AdminRevokeInviteCode)
+			invites.DELETE("/invite-batches/:batch", endpoints.AdminRevokeInviteBatch)
 		}
+	}
 
-		if errors.Is(err, jwt.ErrTokenExpired) {
-			gc.IndentedJSON(
-				http.StatusOK,
-				response.CreateErrorResponseWithText(
-					response.ResponseErrorLauncherTokenExpired,
-					"login expired",
-				),
-			)
+	router.Run("localhost:9001")
+}
 
-			return
-		}
+func importAnnouncementFeed(path string) {
+
+	var (
+		err error
 
-		// some other reason the token is invalid
-		if !decodedToken.Valid {
+		imported int
 
-			fmt.Println("Authenticated API called with otherwise invalid token")
+		file *os.File
+	)
+
+	file, err = os.Open(path)
+	if err != nil {
+		log.Fatalf("Could not open announcement feed: %s", err.Error())
+	}
+	defer file.Close()
 
-			gc.AbortWithStatus(http.StatusBadRequest)
+	imported, err = endpoints.ImportAnnouncements(file)
+	if err != nil {
+		log.Fatalf("Could not import announcement feed: %s", err.Error())
+	}
+
+	fmt.Printf("Imported %d announcements from %s\n", imported, path)
+}
+
+// returns the token of the Authorization header, empty if there is none
+func getBearerToken(gc *gin.Context) string {
+
+	var (
+		authHeader  = gc.Request.Header.Get("Authorization")
+		splitHeader = strings.Split(authHeader, "Bearer ")
+	)
+
+	if len(splitHeader) < 2 {
+		return ""
+	}
+
+	return splitHeader[1]
+}
+
+func GetAuthMiddleware() gin.HandlerFunc {
+
+	return func(gc *gin.Context) {
+
+		if !authenticate(gc) {
 			return
 		}
 
-		// add token to context
-		gc.Set("token", decodedToken)
-		gc.Set("claims", *claims)
-
 		// continue chained execution
 		gc.Next()
 	}
+}
+
+// adds the token and its claims to the context,
+// returns false if the token is missing or invalid and the request was aborted
+func authenticate(gc *gin.Context) bool {
+
+	var (
+		err error
+
+		decodedToken *jwt.Token
+		claims       *jwt.MapClaims
+
+		token = getBearerToken(gc)
+	)
+
+	// token is required from here
+	if token == "" {
+
+		fmt.Println("Authenticated API called without token")
+
+		gc.AbortWithStatus(http.StatusBadRequest)
+		return false
+	}
+
+	// decode token
+	decodedToken, claims, err = utils.ParseToken(token)
+	if err != nil && !errors.Is(err, jwt.ErrTokenExpired) {
+
+		fmt.Printf("Authenticated API called with invalid token: %v\n", err.Error())
+
+		gc.AbortWithStatus(http.StatusBadRequest)
+		return false
+	}
+
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponseWithText(
+				response.ResponseErrorLauncherTokenExpired,
+				"login expired",
+			),
+		)
+
+		gc.Abort()
+		return false
+	}
+
+	// some other reason the token is invalid
+	if !decodedToken.Valid {
+
+		fmt.Println("Authenticated API called with otherwise invalid token")
+
+		gc.AbortWithStatus(http.StatusBadRequest)
+		return false
+	}
+
+	// challenges and other special purpose tokens do not authenticate
+	if _, hasPurpose := (*claims)["purpose"]; hasPurpose {
+
+		fmt.Printf("Authenticated API called with %s token\n", (*claims)["purpose"])
+
+		gc.AbortWithStatus(http.StatusBadRequest)
+		return false
+	}
+
+	// password changes revoke the sessions of the account
+	if statusCode := endpoints.CheckSession(*claims); statusCode != response.ResponseErrorSuccess {
+
+		gc.IndentedJSON(
+			http.StatusOK,
+			response.CreateErrorResponse(statusCode),
+		)
+
+		gc.Abort()
+		return false
+	}
+
+	// add token to context
+	gc.Set("token", decodedToken)
+	gc.Set("claims", *claims)
 
+	return true
 }
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"PSF-LoginAPI/endpoints"
+	"PSF-LoginAPI/response"
+	"PSF-LoginAPI/signature"
+	"PSF-LoginAPI/utils"
+)
+
+// request IDs sent by clients are only used if they are reasonable
+var requestIDRegex = regexp.MustCompile(`^[A-Za-z0-9\-_.]{1,128}$`)
+
+// GetLauncherVersionMiddleware rejects launchers below the minimum version
+// and flags launchers that have a newer version available
+func GetLauncherVersionMiddleware() gin.HandlerFunc {
+
+	return func(gc *gin.Context) {
+
+		var (
+			version, isLauncher        = utils.GetLauncherVersion(gc.Request)
+			minimumVersion, hasMinimum = utils.GetMinimumLauncherVersion()
+		)
+
+		if hasMinimum && (!isLauncher || version.Compare(minimumVersion) < 0) {
+
+			fmt.Printf(
+				"Launcher [%s] is below the minimum version %s\n",
+				gc.Request.UserAgent(),
+				minimumVersion.String(),
+			)
+
+			gc.IndentedJSON(
+				http.StatusOK,
+				response.CreateErrorResponseWithText(
+					response.ResponseErrorUpdateLauncher,
+					fmt.Sprintf("launcher version %s or newer required", minimumVersion.String()),
+				),
+			)
+
+			gc.Abort()
+			return
+		}
+
+		if isLauncher && endpoints.IsLauncherUpdateAvailable(version, endpoints.ResolveLauncherChannel(gc)) {
+
+			gc.Set("updateAvailable", true)
+			gc.Header("X-Launcher-Update-Available", "true")
+		}
+
+		// continue chained execution
+		gc.Next()
+	}
+}
+
+// GetOptionalAuthMiddleware adds the claims of a valid token to the context,
+// requests without or with an invalid token continue unauthenticated
+func GetOptionalAuthMiddleware() gin.HandlerFunc {
+
+	return func(gc *gin.Context) {
+
+		var (
+			token = getBearerToken(gc)
+		)
+
+		if token != "" {
+
+			decodedToken, claims, err := utils.ParseToken(token)
+			_, hasPurpose := (*claims)["purpose"]
+
+			if err == nil && decodedToken.Valid && !hasPurpose && endpoints.CheckSession(*claims) == response.ResponseErrorSuccess {
+				gc.Set("token", decodedToken)
+				gc.Set("claims", *claims)
+			}
+		}
+
+		// continue chained execution
+		gc.Next()
+	}
+}
+
+// GetRateLimitMiddleware rejects requests of IPs that used up their requests of the limiter
+func GetRateLimitMiddleware(limiter *utils.RateLimiter) gin.HandlerFunc {
+
+	return func(gc *gin.Context) {
+
+		if !limiter.Allow(gc.ClientIP()) {
+
+			fmt.Printf("%s rate limited for %s\n", gc.ClientIP(), gc.FullPath())
+
+			gc.IndentedJSON(
+				http.StatusOK,
+				response.CreateErrorResponse(response.ResponseErrorRateLimited),
+			)
+
+			gc.Abort()
+			return
+		}
+
+		// continue chained execution
+		gc.Next()
+	}
+}
+
+// GetAdminMiddleware lets requests with the admin API key or a token with the admin permission through
+func GetAdminMiddleware() gin.HandlerFunc {
+
+	return func(gc *gin.Context) {
+
+		var (
+			adminAPIKey = utils.GetAdminAPIKey()
+			token       = getBearerToken(gc)
+		)
+
+		// the admin API key grants all permissions
+		if len(adminAPIKey) > 0 && subtle.ConstantTimeCompare([]byte(token), adminAPIKey) == 1 {
+
+			gc.Set("permissions", []string{utils.PermissionAll})
+
+			// continue chained execution
+			gc.Next()
+			return
+		}
+
+		if !authenticate(gc) {
+			return
+		}
+
+		// the permissions of the token might be outdated
+		if statusCode := endpoints.LoadRequestPermissions(gc); statusCode != response.ResponseErrorSuccess {
+
+			gc.IndentedJSON(
+				http.StatusOK,
+				response.CreateErrorResponse(statusCode),
+			)
+
+			gc.Abort()
+			return
+		}
+
+		if !requestHasPermission(gc, utils.PermissionAdmin) {
+
+			fmt.Printf("Admin API called without permission by account ID [%s]\n", gc.MustGet("claims").(jwt.MapClaims)["account"])
+
+			gc.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+
+		// continue chained execution
+		gc.Next()
+	}
+}
+
+// GetPermissionMiddleware only lets requests through that have the permission
+func GetPermissionMiddleware(permission string) gin.HandlerFunc {
+
+	return func(gc *gin.Context) {
+
+		if !requestHasPermission(gc, permission) {
+
+			fmt.Printf("%s called without permission %s\n", gc.FullPath(), permission)
+
+			gc.IndentedJSON(
+				http.StatusOK,
+				response.CreateErrorResponse(response.ResponseErrorPermissionDenied),
+			)
+
+			gc.Abort()
+			return
+		}
+
+		// continue chained execution
+		gc.Next()
+	}
+}
+
+// permissions are set by the admin middleware from the API key or the current roles of the account, otherwise taken from the token
+func requestHasPermission(gc *gin.Context, permission string) bool {
+
+	var (
+		permissions []string
+	)
+
+	if value, exists := gc.Get("permissions"); exists {
+		permissions = value.([]string)
+	} else if claims, exists := gc.Get("claims"); exists {
+		permissions = utils.ClaimStrings(claims.(jwt.MapClaims), "permissions")
+	}
+
+	return utils.HasPermission(permissions, permission)
+}
+
+// holds back the response body until it is signed
+type signingResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (writer *signingResponseWriter) Write(data []byte) (int, error) {
+	return writer.body.Write(data)
+}
+
+func (writer *signingResponseWriter) WriteString(data string) (int, error) {
+	return writer.body.WriteString(data)
+}
+
+// GetResponseSigningMiddleware signs the response body, a timestamp and the request ID
+// and adds the signature headers, it does nothing if no signing key is configured
+func GetResponseSigningMiddleware() gin.HandlerFunc {
+
+	return func(gc *gin.Context) {
+
+		var (
+			err error
+
+			requestID string
+			timestamp int64
+
+			privateKey, enabled = utils.GetResponseSigningKey()
+
+			writer = &signingResponseWriter{
+				ResponseWriter: gc.Writer,
+			}
+		)
+
+		if !enabled {
+			gc.Next()
+			return
+		}
+
+		requestID = gc.GetHeader(signature.HeaderRequestID)
+		if !requestIDRegex.MatchString(requestID) {
+
+			randomID := make([]byte, 16)
+
+			_, err = rand.Read(randomID)
+			if err != nil {
+
+				fmt.Printf("Request ID creation failed: %s\n", err.Error())
+
+				gc.AbortWithStatus(http.StatusInternalServerError)
+				return
+			}
+
+			requestID = hex.EncodeToString(randomID)
+		}
+
+		gc.Writer = writer
+
+		// continue chained execution
+		gc.Next()
+
+		gc.Writer = writer.ResponseWriter
+
+		// headers were already sent, e.g. by AbortWithStatus
+		if writer.ResponseWriter.Written() {
+			_, _ = writer.ResponseWriter.Write(writer.body.Bytes())
+			return
+		}
+
+		timestamp = time.Now().Unix()
+
+		gc.Header(signature.HeaderRequestID, requestID)
+		gc.Header(signature.HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+		gc.Header(signature.HeaderSignature, signature.Sign(privateKey, timestamp, requestID, writer.body.Bytes()))
+
+		writer.ResponseWriter.WriteHeaderNow()
+		_, _ = writer.ResponseWriter.Write(writer.body.Bytes())
+	}
+}
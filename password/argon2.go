@@ -0,0 +1,161 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const argon2idPrefix = "$argon2id$"
+
+// default parameters, the second recommended option of RFC 9106
+const (
+	defaultArgon2Memory  = 64 * 1024
+	defaultArgon2Time    = 3
+	defaultArgon2Threads = 4
+
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+// Argon2id hashes are encoded as $argon2id$v=19$m=<memory>,t=<time>,p=<threads>$<salt>$<key>
+type Argon2id struct {
+	// KiB
+	Memory  uint32
+	Time    uint32
+	Threads uint8
+}
+
+type argon2Hash struct {
+	version uint32
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+func (hasher *Argon2id) params() (memory uint32, time uint32, threads uint8) {
+
+	memory, time, threads = hasher.Memory, hasher.Time, hasher.Threads
+
+	if memory == 0 {
+		memory = defaultArgon2Memory
+	}
+
+	if time == 0 {
+		time = defaultArgon2Time
+	}
+
+	if threads == 0 {
+		threads = defaultArgon2Threads
+	}
+
+	return
+}
+
+func (hasher *Argon2id) Name() string {
+	return "argon2id"
+}
+
+func (hasher *Argon2id) Prefixes() []string {
+	return []string{argon2idPrefix}
+}
+
+func (hasher *Argon2id) Hash(password string) (string, error) {
+
+	var (
+		err error
+
+		salt = make([]byte, argon2SaltLength)
+
+		memory, time, threads = hasher.params()
+	)
+
+	_, err = rand.Read(salt)
+	if err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, time, memory, threads, argon2KeyLength)
+
+	return fmt.Sprintf(
+		"%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix,
+		argon2.Version,
+		memory,
+		time,
+		threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (hasher *Argon2id) Verify(hash string, password string) (bool, error) {
+
+	var (
+		err error
+
+		decoded *argon2Hash
+	)
+
+	decoded, err = decodeArgon2Hash(hash)
+	if err != nil {
+		return false, err
+	}
+
+	key := argon2.IDKey([]byte(password), decoded.salt, decoded.time, decoded.memory, decoded.threads, uint32(len(decoded.key)))
+
+	return subtle.ConstantTimeCompare(key, decoded.key) == 1, nil
+}
+
+func (hasher *Argon2id) NeedsRehash(hash string) bool {
+
+	var (
+		memory, time, threads = hasher.params()
+	)
+
+	decoded, err := decodeArgon2Hash(hash)
+	if err != nil {
+		return true
+	}
+
+	return decoded.version != argon2.Version ||
+		decoded.memory != memory ||
+		decoded.time != time ||
+		decoded.threads != threads
+}
+
+func decodeArgon2Hash(hash string) (decoded *argon2Hash, err error) {
+
+	var (
+		// "", "argon2id", version, params, salt, key
+		parts = strings.Split(hash, "$")
+	)
+
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, ErrInvalidHash
+	}
+
+	decoded = &argon2Hash{}
+
+	_, err = fmt.Sscanf(parts[2], "v=%d", &decoded.version)
+	if err == nil {
+		_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &decoded.memory, &decoded.time, &decoded.threads)
+	}
+	if err == nil {
+		decoded.salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	}
+	if err == nil {
+		decoded.key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	}
+	if err != nil || decoded.memory == 0 || decoded.time == 0 || decoded.threads == 0 || len(decoded.key) == 0 {
+		return nil, ErrInvalidHash
+	}
+
+	return
+}
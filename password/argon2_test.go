@@ -0,0 +1,101 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// small parameters to keep the tests fast
+var testArgon2 = &Argon2id{Memory: 1024, Time: 1, Threads: 1}
+
+func TestArgon2idRoundTrip(t *testing.T) {
+
+	hash, err := testArgon2.Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Fatalf("unexpected encoding %s", hash)
+	}
+
+	ok, err := testArgon2.Verify(hash, "password")
+	if err != nil || !ok {
+		t.Fatalf("Verify of the correct password returned %v, %v", ok, err)
+	}
+
+	ok, err = testArgon2.Verify(hash, "wrong password")
+	if err != nil || ok {
+		t.Fatalf("Verify of a wrong password returned %v, %v", ok, err)
+	}
+
+	if testArgon2.NeedsRehash(hash) {
+		t.Error("hash of the same parameters needs rehash")
+	}
+
+	if !(&Argon2id{Memory: 2048, Time: 1, Threads: 1}).NeedsRehash(hash) {
+		t.Error("hash of other parameters needs no rehash")
+	}
+
+	// the package Verify finds the hasher by the prefix
+	ok, rehash, err := Verify(testArgon2, hash, "password")
+	if err != nil || !ok || rehash {
+		t.Fatalf("package Verify returned %v, %v, %v", ok, rehash, err)
+	}
+}
+
+func TestArgon2idTampered(t *testing.T) {
+
+	hash, err := testArgon2.Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := strings.Split(hash, "$")
+
+	// other parameters derive another key
+	tampered := strings.Replace(hash, "t=1", "t=2", 1)
+
+	ok, err := testArgon2.Verify(tampered, "password")
+	if err != nil || ok {
+		t.Errorf("Verify of hash with changed parameters returned %v, %v", ok, err)
+	}
+
+	// flipped key
+	key := []byte(parts[5])
+	if key[0] == 'A' {
+		key[0] = 'B'
+	} else {
+		key[0] = 'A'
+	}
+	parts[5] = string(key)
+
+	ok, err = testArgon2.Verify(strings.Join(parts, "$"), "password")
+	if err != nil || ok {
+		t.Errorf("Verify of hash with changed key returned %v, %v", ok, err)
+	}
+}
+
+func TestArgon2idInvalid(t *testing.T) {
+
+	for _, hash := range []string{
+		"$argon2id$v=19$m=abc,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5",
+		"$argon2id$v=19$m=0,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$not base64$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$",
+		"$argon2i$v=19$m=1024,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5",
+		"$argon2id$m=1024,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA",
+	} {
+
+		ok, err := testArgon2.Verify(hash, "password")
+		if !errors.Is(err, ErrInvalidHash) || ok {
+			t.Errorf("Verify of %s returned %v, %v, expected ErrInvalidHash", hash, ok, err)
+		}
+
+		if !testArgon2.NeedsRehash(hash) {
+			t.Errorf("invalid hash %s needs no rehash", hash)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package password
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type Bcrypt struct {
+	// bcrypt.DefaultCost if not set
+	Cost int
+}
+
+func (hasher *Bcrypt) cost() int {
+
+	if hasher.Cost == 0 {
+		return bcrypt.DefaultCost
+	}
+
+	return hasher.Cost
+}
+
+func (hasher *Bcrypt) Name() string {
+	return "bcrypt"
+}
+
+func (hasher *Bcrypt) Prefixes() []string {
+	return []string{"$2a$", "$2b$", "$2y$"}
+}
+
+func (hasher *Bcrypt) Hash(password string) (string, error) {
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), hasher.cost())
+
+	return string(hash), err
+}
+
+func (hasher *Bcrypt) Verify(hash string, password string) (bool, error) {
+
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func (hasher *Bcrypt) NeedsRehash(hash string) bool {
+
+	cost, err := bcrypt.Cost([]byte(hash))
+
+	return err != nil || cost != hasher.cost()
+}
@@ -0,0 +1,55 @@
+package password
+
+import (
+	"errors"
+	"strings"
+)
+
+const legacyPrefix = "$legacy$"
+
+// legacy verifies the passhash the game server wrote before the password change.
+//
+// The game server (psforever/PSF-LoginServer, net.psforever.login.LoginActor) hashes passwords with
+// the t3hnar scala-bcrypt library, a wrapper of jBCrypt, as bcryptBounded(10) and checks them with isBcryptedSafeBounded.
+// The passhash is the modular crypt string jBCrypt writes, "$2a$10$" followed by the 22 character salt and
+// the 31 character hash, 60 characters in total. The bounded functions refuse passwords longer than 71 bytes,
+// bcrypt here fails for the same passwords, so those accounts could not log in before either.
+//
+// Legacy hashes are only stored in the passhash column, Legacy marks them for Verify.
+type legacy struct{}
+
+// Legacy returns the legacy passhash in the form Verify recognizes
+func Legacy(passhash string) string {
+	return legacyPrefix + passhash
+}
+
+func (hasher legacy) Name() string {
+	return "legacy"
+}
+
+func (hasher legacy) Prefixes() []string {
+	return []string{legacyPrefix}
+}
+
+func (hasher legacy) Hash(string) (string, error) {
+	return "", errors.New("legacy password hashes can only be verified")
+}
+
+func (hasher legacy) Verify(hash string, password string) (bool, error) {
+
+	var (
+		passhash = strings.TrimPrefix(hash, legacyPrefix)
+	)
+
+	// account without a legacy hash
+	if passhash == "" {
+		return false, nil
+	}
+
+	return (&Bcrypt{}).Verify(passhash, password)
+}
+
+// legacy hashes are always replaced
+func (hasher legacy) NeedsRehash(string) bool {
+	return true
+}
@@ -0,0 +1,49 @@
+package password
+
+import (
+	"testing"
+)
+
+func TestLegacy(t *testing.T) {
+
+	// hashes from the test vectors of jBCrypt, the bcrypt implementation the game server hashes with,
+	// the game server writes the same format with cost 10
+	tests := []struct {
+		name     string
+		passhash string
+		password string
+		expected bool
+	}{
+		{"empty password", "$2a$06$DCq7YPn5Rq63x1Lad4cll.TV4S6ytwfsfvkgY8jIucDrjc8deX1s.", "", true},
+		{"single character", "$2a$06$m0CrhHm10qJ3lXRY.5zDGO3rS2KdeeWLuGmsfGlMfOxih58VYVfxe", "a", true},
+		{"short password", "$2a$06$If6bvum7DFjUnE9p2uDeDu0YHzrHM6tf.iqN8.yx.jNN1ILEf7h0i", "abc", true},
+		{"alphabet", "$2a$06$.rCVZVOThsIa97pEDOxvGuRRgzG64bvtJ0938xuqzv18d3ZpQhstC", "abcdefghijklmnopqrstuvwxyz", true},
+		{"wrong password", "$2a$06$If6bvum7DFjUnE9p2uDeDu0YHzrHM6tf.iqN8.yx.jNN1ILEf7h0i", "abd", false},
+		{"no passhash", "", "", false},
+	}
+
+	for _, test := range tests {
+
+		// legacy hashes are always replaced with the current hasher
+		ok, rehash, err := Verify(&Bcrypt{}, Legacy(test.passhash), test.password)
+		if err != nil {
+			t.Errorf("%s: Verify returned %v", test.name, err)
+			continue
+		}
+
+		if ok != test.expected {
+			t.Errorf("%s: Verify = %v, expected %v", test.name, ok, test.expected)
+		}
+
+		if ok && !rehash {
+			t.Errorf("%s: verified legacy hash needs no rehash", test.name)
+		}
+	}
+}
+
+func TestLegacyHash(t *testing.T) {
+
+	if _, err := (legacy{}).Hash("password"); err == nil {
+		t.Error("legacy Hash returned no error")
+	}
+}
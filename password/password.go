@@ -0,0 +1,74 @@
+// Package password hashes passwords and verifies them against hashes of all supported algorithms.
+// The algorithm of a hash is recognized by its prefix.
+package password
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrUnknownAlgorithm = errors.New("unknown password hash algorithm")
+	ErrInvalidHash      = errors.New("invalid password hash")
+)
+
+type Hasher interface {
+	// name of the algorithm
+	Name() string
+	// prefixes of the hashes the hasher creates
+	Prefixes() []string
+	Hash(password string) (string, error)
+	// returns false if the password does not match the hash
+	Verify(hash string, password string) (bool, error)
+	// returns true if the hash was created with other parameters than the ones of the hasher
+	NeedsRehash(hash string) bool
+}
+
+var hashers []Hasher
+
+func init() {
+	Register(&Bcrypt{})
+	Register(&Argon2id{})
+	Register(legacy{})
+}
+
+// Register adds a hasher whose hashes can be verified
+func Register(hasher Hasher) {
+	hashers = append(hashers, hasher)
+}
+
+// Lookup returns the registered hasher that created the hash, nil if there is none
+func Lookup(hash string) Hasher {
+
+	for _, hasher := range hashers {
+		for _, prefix := range hasher.Prefixes() {
+			if strings.HasPrefix(hash, prefix) {
+				return hasher
+			}
+		}
+	}
+
+	return nil
+}
+
+// Verify checks the password against the hash with the algorithm that created it.
+// If the password matches, rehash is true if the hash should be replaced by one of the target hasher.
+func Verify(target Hasher, hash string, password string) (ok bool, rehash bool, err error) {
+
+	var (
+		hasher = Lookup(hash)
+	)
+
+	if hasher == nil {
+		return false, false, ErrUnknownAlgorithm
+	}
+
+	ok, err = hasher.Verify(hash, password)
+	if err != nil || !ok {
+		return false, false, err
+	}
+
+	rehash = hasher.Name() != target.Name() || target.NeedsRehash(hash)
+
+	return
+}
@@ -0,0 +1,64 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	ErrTooShort         = errors.New("password is too short")
+	ErrTooLong          = errors.New("password is too long")
+	ErrTooSimple        = errors.New("password does not use enough character classes")
+	ErrContainsUsername = errors.New("password contains the username")
+)
+
+// Policy are the rules new passwords have to follow
+type Policy struct {
+	// characters
+	MinLength int
+	// bytes, bcrypt only uses the first 72 bytes
+	MaxLength int
+	// how many of lowercase letters, uppercase letters, digits and other characters have to be used
+	MinClasses int
+}
+
+// Check returns the first rule the password breaks, nil if it follows the policy
+func (policy *Policy) Check(password string, username string) error {
+
+	var (
+		lower, upper, digit, other int
+	)
+
+	if utf8.RuneCountInString(password) < policy.MinLength {
+		return ErrTooShort
+	}
+
+	if policy.MaxLength > 0 && len(password) > policy.MaxLength {
+		return ErrTooLong
+	}
+
+	for _, character := range password {
+		switch {
+		case unicode.IsLower(character):
+			lower = 1
+		case unicode.IsUpper(character):
+			upper = 1
+		case unicode.IsDigit(character):
+			digit = 1
+		default:
+			other = 1
+		}
+	}
+
+	if lower+upper+digit+other < policy.MinClasses {
+		return ErrTooSimple
+	}
+
+	if username != "" && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		return ErrContainsUsername
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package password
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// ErrOverloaded is returned instead of queueing more work than the pool allows
+var ErrOverloaded = errors.New("password pool overloaded")
+
+// Pool limits how many passwords are hashed and verified at the same time.
+// Work that can not start right away is queued up to the queue depth, more work is rejected with ErrOverloaded.
+type Pool struct {
+	workers    chan struct{}
+	queued     atomic.Int32
+	queueDepth int32
+}
+
+func NewPool(workers int, queueDepth int) *Pool {
+	return &Pool{
+		workers:    make(chan struct{}, workers),
+		queueDepth: int32(queueDepth),
+	}
+}
+
+// waits for a free worker, the returned function frees it again
+func (pool *Pool) acquire(ctx context.Context) (release func(), err error) {
+
+	release = func() {
+		<-pool.workers
+	}
+
+	select {
+	case pool.workers <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	// shed load instead of letting the queue grow
+	if pool.queued.Add(1) > pool.queueDepth {
+		pool.queued.Add(-1)
+		return nil, ErrOverloaded
+	}
+	defer pool.queued.Add(-1)
+
+	select {
+	case pool.workers <- struct{}{}:
+		return release, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Verify runs the package Verify on a worker of the pool
+func (pool *Pool) Verify(ctx context.Context, target Hasher, hash string, password string) (ok bool, rehash bool, err error) {
+
+	var (
+		release func()
+	)
+
+	release, err = pool.acquire(ctx)
+	if err != nil {
+		return
+	}
+	defer release()
+
+	return Verify(target, hash, password)
+}
+
+// Hash hashes the password with the hasher on a worker of the pool
+func (pool *Pool) Hash(ctx context.Context, hasher Hasher, password string) (hash string, err error) {
+
+	var (
+		release func()
+	)
+
+	release, err = pool.acquire(ctx)
+	if err != nil {
+		return
+	}
+	defer release()
+
+	return hasher.Hash(password)
+}
@@ -0,0 +1,185 @@
+package password
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blocks every hash until released, so the tests control how many workers are busy
+type blockingHasher struct {
+	Bcrypt
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlockingHasher() *blockingHasher {
+	return &blockingHasher{
+		started: make(chan struct{}, 16),
+		release: make(chan struct{}),
+	}
+}
+
+func (hasher *blockingHasher) Hash(password string) (string, error) {
+
+	hasher.started <- struct{}{}
+	<-hasher.release
+
+	return password, nil
+}
+
+// waits until the queue of the pool has n entries
+func waitQueued(t *testing.T, pool *Pool, n int32) {
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for pool.queued.Load() != n {
+
+		if time.Now().After(deadline) {
+			t.Fatalf("queue has %d entries, expected %d", pool.queued.Load(), n)
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPoolShedsWhenQueueIsFull(t *testing.T) {
+
+	var (
+		pool    = NewPool(1, 2)
+		hasher  = newBlockingHasher()
+		results = make(chan error, 3)
+	)
+
+	hash := func() {
+		_, err := pool.Hash(context.Background(), hasher, "password")
+		results <- err
+	}
+
+	// one on the worker, two in the queue
+	go hash()
+	<-hasher.started
+
+	go hash()
+	go hash()
+	waitQueued(t, pool, 2)
+
+	_, err := pool.Hash(context.Background(), hasher, "password")
+	if !errors.Is(err, ErrOverloaded) {
+		t.Fatalf("Hash with full queue returned %v, expected ErrOverloaded", err)
+	}
+
+	_, _, err = pool.Verify(context.Background(), &Bcrypt{}, "$2a$04$invalid", "password")
+	if !errors.Is(err, ErrOverloaded) {
+		t.Fatalf("Verify with full queue returned %v, expected ErrOverloaded", err)
+	}
+
+	close(hasher.release)
+
+	for i := 0; i < 3; i++ {
+		if err = <-results; err != nil {
+			t.Fatalf("queued Hash returned %v", err)
+		}
+	}
+
+	if pool.queued.Load() != 0 {
+		t.Fatalf("queue has %d entries after all work finished", pool.queued.Load())
+	}
+}
+
+func TestPoolCancelledContext(t *testing.T) {
+
+	var (
+		pool    = NewPool(1, 1)
+		hasher  = newBlockingHasher()
+		results = make(chan error, 1)
+
+		ctx, cancel = context.WithCancel(context.Background())
+	)
+	defer close(hasher.release)
+
+	go func() {
+		_, _ = pool.Hash(context.Background(), hasher, "password")
+	}()
+	<-hasher.started
+
+	// cancelled while queued
+	go func() {
+		_, err := pool.Hash(ctx, hasher, "password")
+		results <- err
+	}()
+	waitQueued(t, pool, 1)
+
+	cancel()
+
+	if err := <-results; !errors.Is(err, context.Canceled) {
+		t.Fatalf("queued Hash returned %v after cancel, expected context.Canceled", err)
+	}
+
+	// cancelled before
+	_, _, err := pool.Verify(ctx, &Bcrypt{}, "$2a$04$invalid", "password")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Verify with cancelled context returned %v, expected context.Canceled", err)
+	}
+}
+
+// BenchmarkPoolVerify verifies bcrypt hashes from more goroutines than the pool has workers and queue entries,
+// verifications beyond that are shed and reported as shed/op
+func BenchmarkPoolVerify(b *testing.B) {
+
+	var (
+		hasher = &Bcrypt{Cost: 4}
+	)
+
+	hash, err := hasher.Hash("password")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	benchmarks := []struct {
+		name        string
+		workers     int
+		queueDepth  int
+		parallelism int
+	}{
+		{"Capacity", 4, 64, 1},
+		{"Burst", 2, 4, 16},
+	}
+
+	for _, benchmark := range benchmarks {
+
+		b.Run(benchmark.name, func(b *testing.B) {
+
+			var (
+				verified atomic.Int64
+				shed     atomic.Int64
+
+				pool = NewPool(benchmark.workers, benchmark.queueDepth)
+			)
+
+			b.SetParallelism(benchmark.parallelism)
+			b.ResetTimer()
+
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+
+					ok, _, err := pool.Verify(context.Background(), hasher, hash, "password")
+
+					switch {
+					case errors.Is(err, ErrOverloaded):
+						shed.Add(1)
+					case err != nil || !ok:
+						b.Errorf("Verify returned %v, %v", ok, err)
+					default:
+						verified.Add(1)
+					}
+				}
+			})
+
+			b.ReportMetric(float64(verified.Load())/float64(b.N), "verified/op")
+			b.ReportMetric(float64(shed.Load())/float64(b.N), "shed/op")
+		})
+	}
+}
@@ -1,5 +1,9 @@
 package response
 
+import (
+	"PSF-LoginAPI/signature"
+)
+
 // Status Codes
 const (
 	ResponseErrorGroupOK = iota * 100
@@ -7,6 +11,8 @@ const (
 	ResponseErrorGroupErrorAccount
 	ResponseErrorGroupErrorDB
 	ResponseErrorGroupErrorInternal
+	ResponseErrorGroupErrorMode
+	ResponseErrorGroupErrorRequest
 )
 
 //
@@ -26,13 +32,39 @@ const (
 	ResponseErrorCorruptFiles
 	ResponseErrorLauncherNoLongerSupported
 	ResponseErrorLauncherGameTokenRequestNotVerified
+	ResponseErrorUnsupportedHashAlgorithm
+	ResponseErrorUnknownLauncherChannel
 )
 
 // Account Error
 const (
+	// no longer returned, legacy password hashes are migrated on login
 	ResponseErrorUseStagingLoginToUpdatePassword = iota + ResponseErrorGroupErrorAccount
 	ResponseErrorWrongUsernamePassword
 	ResponseErrorAccountInactive
+	ResponseErrorUnknownAccount
+	ResponseErrorPermissionDenied
+	ResponseErrorUnknownRole
+	ResponseErrorAccountBanned
+	ResponseErrorAddressBanned
+	ResponseErrorUnknownBan
+	ResponseErrorPasswordPolicy
+	ResponseErrorInvalidUsername
+	ResponseErrorUsernameTaken
+	ResponseErrorInvalidInviteCode
+	ResponseErrorInviteCodeRedeemed
+	ResponseErrorInvalidEmail
+	ResponseErrorEmailTaken
+	// email verification or password reset code unknown, used or expired
+	ResponseErrorInvalidCode
+	// login has to be completed with the code of the authenticator or a recovery code
+	ResponseErrorTwoFactorRequired
+	ResponseErrorWrongTwoFactorCode
+	ResponseErrorTwoFactorEnabled
+	ResponseErrorTwoFactorNotEnabled
+	// login from an untrusted device has to be completed with the code sent to the email
+	ResponseErrorDeviceConfirmationRequired
+	ResponseErrorUnknownDevice
 )
 
 // DB Error
@@ -43,6 +75,25 @@ const (
 // Internal Error
 const (
 	ResponseErrorInternalTokenCreationFailed = iota + ResponseErrorGroupErrorInternal
+	// too many logins at once, try again later
+	ResponseErrorInternalServerBusy
+	ResponseErrorInternalMailFailed
+	ResponseErrorInternalPasswordHashFailed
+)
+
+// Mode Error
+const (
+	ResponseErrorUnknownMode = iota + ResponseErrorGroupErrorMode
+	ResponseErrorModeDisabled
+	ResponseErrorModeAccessDenied
+	ResponseErrorModeMaintenance
+	ResponseErrorMaintenance
+)
+
+// Request Error
+const (
+	ResponseErrorInvalidFeed = iota + ResponseErrorGroupErrorRequest
+	ResponseErrorRateLimited
 )
 
 type DefaultResponse struct {
@@ -57,17 +108,247 @@ type ErrorResponse struct {
 type TokenResponse struct {
 	DefaultResponse
 	Token string `json:"token"`
+	// a newer launcher is available, but the current one is still supported
+	UpdateAvailable bool `json:"updateAvailable,omitempty"`
+}
+
+type ChallengeResponse struct {
+	DefaultResponse
+	Challenge string `json:"challenge"`
+}
+
+type SigningKeyResponse struct {
+	DefaultResponse
+	Algorithm string `json:"algorithm"`
+	// base64 encoded
+	PublicKey string `json:"publicKey"`
 }
 
 type VersionResponse struct {
 	DefaultResponse
 	ReleaseDate   int64  `json:"releaseDate"`
 	VersionString string `json:"versionString"`
+	Channel       string `json:"channel"`
+}
+
+type FileRule struct {
+	File string `json:"file"`
+	Rule string `json:"rule"`
+	Glob bool   `json:"glob"`
+}
+
+type LauncherChannel struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Public      bool    `json:"public"`
+	Accounts    []int64 `json:"accounts"`
+}
+
+type Role struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+type RolesResponse struct {
+	DefaultResponse
+	Roles []Role `json:"roles"`
+}
+
+type AccountRolesResponse struct {
+	DefaultResponse
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+}
+
+type LauncherChannelsResponse struct {
+	DefaultResponse
+	Channels []LauncherChannel `json:"channels"`
+}
+
+type LauncherArtifact struct {
+	Platform    string   `json:"platform"`
+	Arch        string   `json:"arch"`
+	DownloadURL string   `json:"downloadUrl"`
+	Mirrors     []string `json:"mirrors"`
+	Size        int64    `json:"size"`
+	// hex encoded SHA-256 of the download
+	SHA256 string `json:"sha256"`
+	// base64 encoded Ed25519 signature of the download
+	Signature string `json:"signature"`
+}
+
+type VersionResponseV2 struct {
+	VersionResponse
+	// artifact of the requested platform
+	LauncherArtifact
+	// markdown
+	Changelog string `json:"changelog"`
+	Mandatory bool   `json:"mandatory"`
+	MinimumOS string `json:"minimumOS"`
+	// artifacts of all platforms
+	Artifacts []LauncherArtifact `json:"artifacts"`
+}
+
+type Mode struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Maintenance bool   `json:"maintenance"`
+}
+
+type ModesResponse struct {
+	DefaultResponse
+	Modes []Mode `json:"modes"`
+}
+
+type MaintenanceResponse struct {
+	DefaultResponse
+	Message string `json:"message"`
+	// unix time the maintenance is expected to end, 0 if unknown
+	EndsAt int64 `json:"endsAt"`
+}
+
+type MaintenanceWindow struct {
+	ID int64 `json:"id"`
+	// null for global maintenance
+	Mode     *int64 `json:"mode"`
+	StartsAt int64  `json:"startsAt"`
+	EndsAt   int64  `json:"endsAt"`
+	Message  string `json:"message"`
+	Active   bool   `json:"active"`
+}
+
+type MaintenanceWindowsResponse struct {
+	DefaultResponse
+	Windows []MaintenanceWindow `json:"windows"`
+}
+
+type Announcement struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+	// markdown
+	Body      string   `json:"body"`
+	Priority  int32    `json:"priority"`
+	Channels  []string `json:"channels"`
+	Modes     []int64  `json:"modes"`
+	Roles     []string `json:"roles"`
+	PublishAt int64    `json:"publishAt"`
+	// 0 if the announcement does not expire
+	ExpireAt int64 `json:"expireAt"`
+}
+
+type AnnouncementsResponse struct {
+	DefaultResponse
+	Announcements []Announcement `json:"announcements"`
+}
+
+type BanResponse struct {
+	DefaultResponse
+	Reason string `json:"reason"`
+	// unix time the ban ends, 0 if it is permanent
+	EndsAt int64 `json:"endsAt"`
+}
+
+type Ban struct {
+	ID int64 `json:"id"`
+	// null for IP bans
+	Account *int64 `json:"account"`
+	// null for account bans
+	CIDR   *string `json:"cidr"`
+	Reason string  `json:"reason"`
+	// null for bans issued with the admin API key
+	IssuedBy *int64 `json:"issuedBy"`
+	StartsAt int64  `json:"startsAt"`
+	// 0 if the ban is permanent
+	ExpiresAt int64 `json:"expiresAt"`
+	// 0 if the ban was not lifted
+	LiftedAt int64  `json:"liftedAt"`
+	LiftedBy *int64 `json:"liftedBy"`
+	Active   bool   `json:"active"`
+}
+
+type BansResponse struct {
+	DefaultResponse
+	Bans []Ban `json:"bans"`
+}
+
+type InviteCode struct {
+	Code   string `json:"code"`
+	Batch  string `json:"batch"`
+	Target string `json:"target"`
+	// null for registration codes
+	Mode      *int64 `json:"mode"`
+	MaxUses   int32  `json:"maxUses"`
+	Uses      int32  `json:"uses"`
+	CreatedAt int64  `json:"createdAt"`
+	// 0 if the code does not expire
+	ExpiresAt int64 `json:"expiresAt"`
+	// 0 if the code was not revoked
+	RevokedAt int64 `json:"revokedAt"`
+	// null for codes created with the admin API key
+	CreatedBy *int64 `json:"createdBy"`
+	// accounts that redeemed the code
+	Accounts []int64 `json:"accounts"`
+	Active   bool    `json:"active"`
+}
+
+type InviteCodesResponse struct {
+	DefaultResponse
+	Codes []InviteCode `json:"codes"`
+}
+
+type TwoFactorChallengeResponse struct {
+	DefaultResponse
+	// sent back with the code to complete the login
+	Challenge string `json:"challenge"`
+}
+
+type TwoFactorEnrollmentResponse struct {
+	DefaultResponse
+	// otpauth URI for authenticator apps
+	URI string `json:"uri"`
+	// base32 encoded, for entering it manually
+	Secret string `json:"secret"`
+}
+
+type RecoveryCodesResponse struct {
+	DefaultResponse
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+type Device struct {
+	ID        int64 `json:"id"`
+	FirstSeen int64 `json:"firstSeen"`
+	LastSeen  int64 `json:"lastSeen"`
+	// address of the last login
+	IP       *string `json:"ip"`
+	Launcher string  `json:"launcher"`
+	Platform string  `json:"platform"`
+	// confirmed with the second factor or an emailed code
+	Trusted bool `json:"trusted"`
+}
+
+type DevicesResponse struct {
+	DefaultResponse
+	Devices []Device `json:"devices"`
+}
+
+type ImportResponse struct {
+	DefaultResponse
+	Imported int `json:"imported"`
+}
+
+type IDResponse struct {
+	DefaultResponse
+	ID int64 `json:"id"`
 }
 
 type ValidateResponse struct {
 	DefaultResponse
-	Files []string `json:"files"`
+	Files      []string   `json:"files"`
+	Rules      []FileRule `json:"rules"`
+	Algorithms []string   `json:"algorithms"`
 }
 
 type GameTokenResponse struct {
@@ -75,6 +356,13 @@ type GameTokenResponse struct {
 	GameToken string `json:"gameToken"`
 }
 
+type GameTokenResponseV2 struct {
+	DefaultResponse
+	signature.Connection
+	// base64 encoded Ed25519 signature of the connection, empty if response signing is disabled
+	Signature string `json:"signature"`
+}
+
 func CreateErrorResponse(statusCode int) ErrorResponse {
 	return CreateErrorResponseWithText(statusCode, "")
 }
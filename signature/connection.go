@@ -0,0 +1,61 @@
+package signature
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+)
+
+// Connection is the server the launcher connects to with the game token
+type Connection struct {
+	GameToken  string   `json:"gameToken"`
+	Mode       int64    `json:"mode"`
+	WorldHost  string   `json:"worldHost"`
+	WorldPort  int32    `json:"worldPort"`
+	LaunchArgs []string `json:"launchArgs"`
+	// unix time the connection was issued at
+	IssuedAt int64 `json:"issuedAt"`
+}
+
+// message is the JSON encoding of the connection, fields in declaration order
+func (connection *Connection) message() ([]byte, error) {
+	return json.Marshal(connection)
+}
+
+// SignConnection returns the base64 encoded signature of the connection
+func SignConnection(privateKey ed25519.PrivateKey, connection *Connection) (string, error) {
+
+	message, err := connection.message()
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, message)), nil
+}
+
+// VerifyConnection checks the base64 encoded signature of the connection
+func VerifyConnection(publicKey ed25519.PublicKey, connection *Connection, signature string) error {
+
+	var (
+		err error
+
+		message          []byte
+		decodedSignature []byte
+	)
+
+	decodedSignature, err = base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	message, err = connection.message()
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(publicKey, message, decodedSignature) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
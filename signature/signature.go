@@ -0,0 +1,134 @@
+// Package signature signs API responses and verifies them.
+// It only depends on the standard library so launchers written in Go can use it to verify responses.
+package signature
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Response headers
+const (
+	// base64 encoded Ed25519 signature of the message
+	HeaderSignature = "X-Signature"
+	// unix time the response was signed at
+	HeaderTimestamp = "X-Signature-Timestamp"
+	// request ID sent by the client or generated by the API
+	HeaderRequestID = "X-Request-ID"
+)
+
+var (
+	ErrMissingHeader    = errors.New("signature header missing")
+	ErrInvalidSignature = errors.New("signature invalid")
+	ErrRequestID        = errors.New("response request ID does not match")
+	ErrTimestampSkew    = errors.New("signature timestamp out of range")
+)
+
+// Message returns the bytes that are signed for a response
+func Message(timestamp int64, requestID string, body []byte) []byte {
+
+	var (
+		header = fmt.Sprintf("%d\n%s\n", timestamp, requestID)
+	)
+
+	return append([]byte(header), body...)
+}
+
+// Sign returns the base64 encoded signature of the response
+func Sign(privateKey ed25519.PrivateKey, timestamp int64, requestID string, body []byte) string {
+	return base64.StdEncoding.EncodeToString(
+		ed25519.Sign(privateKey, Message(timestamp, requestID, body)),
+	)
+}
+
+// Verify checks the base64 encoded signature of the response
+func Verify(publicKey ed25519.PublicKey, timestamp int64, requestID string, body []byte, signature string) error {
+
+	var (
+		err error
+
+		decodedSignature []byte
+	)
+
+	decodedSignature, err = base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	if !ed25519.Verify(publicKey, Message(timestamp, requestID, body), decodedSignature) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// VerifyResponse checks the signature headers of a response against its body.
+// If requestID is not empty the response has to be for that request.
+// If maxSkew is not zero the signature has to be at most that far from the current time.
+func VerifyResponse(
+	publicKey ed25519.PublicKey,
+	header http.Header,
+	body []byte,
+	requestID string,
+	maxSkew time.Duration,
+) error {
+
+	var (
+		err error
+
+		timestamp int64
+
+		responseSignature = header.Get(HeaderSignature)
+		responseTimestamp = header.Get(HeaderTimestamp)
+		responseRequestID = header.Get(HeaderRequestID)
+	)
+
+	if responseSignature == "" || responseTimestamp == "" || responseRequestID == "" {
+		return ErrMissingHeader
+	}
+
+	if requestID != "" && requestID != responseRequestID {
+		return ErrRequestID
+	}
+
+	timestamp, err = strconv.ParseInt(responseTimestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	if maxSkew != 0 {
+
+		skew := time.Since(time.Unix(timestamp, 0))
+		if skew > maxSkew || skew < -maxSkew {
+			return ErrTimestampSkew
+		}
+	}
+
+	return Verify(publicKey, timestamp, responseRequestID, body, responseSignature)
+}
+
+// ParsePublicKey decodes a base64 encoded Ed25519 public key as published by the API
+func ParsePublicKey(encoded string) (ed25519.PublicKey, error) {
+
+	var (
+		err error
+
+		publicKey []byte
+	)
+
+	publicKey, err = base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(publicKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public key has %d bytes instead of %d", len(publicKey), ed25519.PublicKeySize)
+	}
+
+	return publicKey, nil
+}
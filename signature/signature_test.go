@@ -0,0 +1,140 @@
+package signature
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// fixed seed so failures are reproducible
+var testSeed = []byte("0123456789abcdef0123456789abcdef")
+
+func TestSignVerify(t *testing.T) {
+
+	var (
+		privateKey  = ed25519.NewKeyFromSeed(testSeed)
+		publicKey   = privateKey.Public().(ed25519.PublicKey)
+		otherKey    = ed25519.NewKeyFromSeed([]byte("fedcba9876543210fedcba9876543210"))
+		otherPublic = otherKey.Public().(ed25519.PublicKey)
+
+		timestamp = int64(1700000000)
+		requestID = "request"
+		body      = []byte(`{"status":0}`)
+		signature = Sign(privateKey, timestamp, requestID, body)
+	)
+
+	tests := []struct {
+		name      string
+		publicKey ed25519.PublicKey
+		timestamp int64
+		requestID string
+		body      []byte
+		signature string
+		expected  error
+	}{
+		{"valid", publicKey, timestamp, requestID, body, signature, nil},
+		{"empty body", publicKey, timestamp, requestID, nil, Sign(privateKey, timestamp, requestID, nil), nil},
+		{"other key", otherPublic, timestamp, requestID, body, signature, ErrInvalidSignature},
+		{"changed timestamp", publicKey, timestamp + 1, requestID, body, signature, ErrInvalidSignature},
+		{"changed request ID", publicKey, timestamp, "other", body, signature, ErrInvalidSignature},
+		{"changed body", publicKey, timestamp, requestID, []byte(`{"status":1}`), signature, ErrInvalidSignature},
+		{"not base64", publicKey, timestamp, requestID, body, "not base64!", ErrInvalidSignature},
+		{"empty signature", publicKey, timestamp, requestID, body, "", ErrInvalidSignature},
+	}
+
+	for _, test := range tests {
+
+		err := Verify(test.publicKey, test.timestamp, test.requestID, test.body, test.signature)
+		if !errors.Is(err, test.expected) {
+			t.Errorf("%s: Verify returned %v, expected %v", test.name, err, test.expected)
+		}
+	}
+}
+
+func TestVerifyResponse(t *testing.T) {
+
+	var (
+		privateKey = ed25519.NewKeyFromSeed(testSeed)
+		publicKey  = privateKey.Public().(ed25519.PublicKey)
+
+		now  = time.Now().Unix()
+		old  = now - 3600
+		body = []byte(`{"status":0}`)
+	)
+
+	signedHeader := func(timestamp int64, requestID string) http.Header {
+
+		header := http.Header{}
+		header.Set(HeaderSignature, Sign(privateKey, timestamp, requestID, body))
+		header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+		header.Set(HeaderRequestID, requestID)
+
+		return header
+	}
+
+	withoutHeader := func(header http.Header, name string) http.Header {
+		header.Del(name)
+		return header
+	}
+
+	tests := []struct {
+		name      string
+		header    http.Header
+		requestID string
+		maxSkew   time.Duration
+		expected  error
+	}{
+		{"valid", signedHeader(now, "request"), "request", time.Minute, nil},
+		{"any request ID", signedHeader(now, "request"), "", time.Minute, nil},
+		{"no skew check", signedHeader(old, "request"), "request", 0, nil},
+		{"other request", signedHeader(now, "request"), "other", time.Minute, ErrRequestID},
+		{"too old", signedHeader(old, "request"), "request", time.Minute, ErrTimestampSkew},
+		{"missing signature", withoutHeader(signedHeader(now, "request"), HeaderSignature), "", 0, ErrMissingHeader},
+		{"missing timestamp", withoutHeader(signedHeader(now, "request"), HeaderTimestamp), "", 0, ErrMissingHeader},
+		{"missing request ID", withoutHeader(signedHeader(now, "request"), HeaderRequestID), "", 0, ErrMissingHeader},
+	}
+
+	for _, test := range tests {
+
+		err := VerifyResponse(publicKey, test.header, body, test.requestID, test.maxSkew)
+		if !errors.Is(err, test.expected) {
+			t.Errorf("%s: VerifyResponse returned %v, expected %v", test.name, err, test.expected)
+		}
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+
+	var (
+		publicKey = ed25519.NewKeyFromSeed(testSeed).Public().(ed25519.PublicKey)
+	)
+
+	tests := []struct {
+		name    string
+		encoded string
+		valid   bool
+	}{
+		{"valid", base64.StdEncoding.EncodeToString(publicKey), true},
+		{"too short", base64.StdEncoding.EncodeToString(publicKey[:16]), false},
+		{"not base64", "not base64!", false},
+		{"empty", "", false},
+	}
+
+	for _, test := range tests {
+
+		parsed, err := ParsePublicKey(test.encoded)
+
+		if (err == nil) != test.valid {
+			t.Errorf("%s: ParsePublicKey returned %v, expected valid %v", test.name, err, test.valid)
+			continue
+		}
+
+		if test.valid && !parsed.Equal(publicKey) {
+			t.Errorf("%s: ParsePublicKey returned a different key", test.name)
+		}
+	}
+}
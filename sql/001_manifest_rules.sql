@@ -0,0 +1,13 @@
+-- per file validation rules for the filehash manifest
+ALTER TABLE "filehash"
+	ADD COLUMN IF NOT EXISTS "rule" VARCHAR(16) NOT NULL DEFAULT 'required'
+		CHECK ("rule" IN ('required', 'optional', 'presence')),
+	ADD COLUMN IF NOT EXISTS "glob" BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- known good alternate hashes for a filehash entry
+CREATE TABLE IF NOT EXISTS "filehash_alternate" (
+	"mode" INTEGER NOT NULL,
+	"file" TEXT NOT NULL,
+	"hash" TEXT NOT NULL,
+	PRIMARY KEY ("mode", "file", "hash")
+);
@@ -0,0 +1,8 @@
+-- launchers that may still validate files with the legacy SHA1 aggregate hash,
+-- all launchers released before the column was added only know SHA1
+ALTER TABLE "launcher"
+	ADD COLUMN IF NOT EXISTS "allow_sha1" BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- new launchers use the newer algorithms
+ALTER TABLE "launcher"
+	ALTER COLUMN "allow_sha1" SET DEFAULT FALSE;
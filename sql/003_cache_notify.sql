@@ -0,0 +1,22 @@
+-- notifies the API to drop cached data of the topic passed as trigger argument
+CREATE OR REPLACE FUNCTION "loginapi_notify_cache"() RETURNS TRIGGER AS $$
+BEGIN
+	PERFORM pg_notify('loginapi_cache', TG_ARGV[0]);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS "filehash_notify_cache" ON "filehash";
+CREATE TRIGGER "filehash_notify_cache"
+	AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON "filehash"
+	FOR EACH STATEMENT EXECUTE FUNCTION "loginapi_notify_cache"('manifest');
+
+DROP TRIGGER IF EXISTS "filehash_alternate_notify_cache" ON "filehash_alternate";
+CREATE TRIGGER "filehash_alternate_notify_cache"
+	AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON "filehash_alternate"
+	FOR EACH STATEMENT EXECUTE FUNCTION "loginapi_notify_cache"('manifest');
+
+DROP TRIGGER IF EXISTS "launcher_notify_cache" ON "launcher";
+CREATE TRIGGER "launcher_notify_cache"
+	AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON "launcher"
+	FOR EACH STATEMENT EXECUTE FUNCTION "loginapi_notify_cache"('launcher');
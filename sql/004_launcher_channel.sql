@@ -0,0 +1,33 @@
+-- release channels launchers are published on
+CREATE TABLE IF NOT EXISTS "launcher_channel" (
+	"name" VARCHAR(32) PRIMARY KEY,
+	"description" TEXT NOT NULL DEFAULT '',
+	-- public channels can be selected by every launcher
+	"public" BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+INSERT INTO "launcher_channel" ("name", "description", "public") VALUES
+	('stable', 'Stable releases', TRUE),
+	('beta', 'Beta releases for testers', FALSE),
+	('nightly', 'Nightly builds', FALSE)
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE "launcher"
+	ADD COLUMN IF NOT EXISTS "channel" VARCHAR(32) NOT NULL DEFAULT 'stable'
+		REFERENCES "launcher_channel" ("name");
+
+-- channel an account is assigned to by an admin
+CREATE TABLE IF NOT EXISTS "account_launcher_channel" (
+	"account_id" INTEGER PRIMARY KEY REFERENCES "account" ("id") ON DELETE CASCADE,
+	"channel" VARCHAR(32) NOT NULL REFERENCES "launcher_channel" ("name") ON DELETE CASCADE
+);
+
+DROP TRIGGER IF EXISTS "launcher_channel_notify_cache" ON "launcher_channel";
+CREATE TRIGGER "launcher_channel_notify_cache"
+	AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON "launcher_channel"
+	FOR EACH STATEMENT EXECUTE FUNCTION "loginapi_notify_cache"('launcher');
+
+DROP TRIGGER IF EXISTS "account_launcher_channel_notify_cache" ON "account_launcher_channel";
+CREATE TRIGGER "account_launcher_channel_notify_cache"
+	AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON "account_launcher_channel"
+	FOR EACH STATEMENT EXECUTE FUNCTION "loginapi_notify_cache"('launcher_channel');
@@ -0,0 +1,6 @@
+-- release metadata returned by /v2/version, the download metadata is per build in "launcher_artifact"
+ALTER TABLE "launcher"
+	ADD COLUMN IF NOT EXISTS "changelog" TEXT NOT NULL DEFAULT '',
+	-- launchers are told to update before they continue
+	ADD COLUMN IF NOT EXISTS "mandatory" BOOLEAN NOT NULL DEFAULT FALSE,
+	ADD COLUMN IF NOT EXISTS "min_os" TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,92 @@
+-- platform specific builds of a launcher release
+CREATE TABLE IF NOT EXISTS "launcher_artifact" (
+	"launcher_version" VARCHAR(32) NOT NULL,
+	-- operating system, e.g. windows or linux-wine
+	"platform" VARCHAR(32) NOT NULL,
+	-- CPU architecture, e.g. x86 or x64
+	"arch" VARCHAR(16) NOT NULL,
+	"hash" TEXT NOT NULL UNIQUE,
+	"download_url" TEXT NOT NULL DEFAULT '',
+	"mirrors" TEXT[] NOT NULL DEFAULT '{}',
+	"size" BIGINT NOT NULL DEFAULT 0,
+	-- hex encoded SHA-256 of the download
+	"sha256" VARCHAR(64) NOT NULL DEFAULT '',
+	-- base64 encoded Ed25519 signature of the download made with the release key
+	"signature" TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY ("launcher_version", "platform", "arch")
+);
+
+-- before, every build was its own launcher row with a hash and rows of different platforms could share a version
+DO $$
+BEGIN
+	IF EXISTS (
+		SELECT 1
+		FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = 'launcher' AND column_name = 'hash'
+	) THEN
+
+		-- every row becomes a build of its version, the first row of a version is the windows x86 build,
+		-- the platform of the others is unknown and has to be set by an admin
+		INSERT INTO "launcher_artifact" ("launcher_version", "platform", "arch", "hash")
+		SELECT
+			"version",
+			CASE WHEN "build" = 1 THEN 'windows' ELSE 'unknown' END,
+			CASE WHEN "build" = 1 THEN 'x86' ELSE 'build-' || "build" END,
+			"hash"
+		FROM (
+			SELECT "version", "hash", ROW_NUMBER() OVER (PARTITION BY "version" ORDER BY "released_at", ctid) AS "build"
+			FROM "launcher"
+		) AS "builds";
+
+		-- the release is active and allows SHA1 if any of its builds did
+		UPDATE "launcher" SET "active" = TRUE
+		WHERE NOT "active" AND "version" IN (SELECT "version" FROM "launcher" WHERE "active");
+
+		UPDATE "launcher" SET "allow_sha1" = TRUE
+		WHERE NOT "allow_sha1" AND "version" IN (SELECT "version" FROM "launcher" WHERE "allow_sha1");
+
+		-- merge the rows of a version into the first one
+		DELETE FROM "launcher"
+		WHERE ctid IN (
+			SELECT ctid
+			FROM (
+				SELECT ctid, ROW_NUMBER() OVER (PARTITION BY "version" ORDER BY "released_at", ctid) AS "build"
+				FROM "launcher"
+			) AS "builds"
+			WHERE "build" > 1
+		);
+
+		ALTER TABLE "launcher" DROP COLUMN "hash";
+	END IF;
+END
+$$;
+
+-- a launcher release is identified by its version
+CREATE UNIQUE INDEX IF NOT EXISTS "launcher_version_key" ON "launcher" ("version");
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'launcher_artifact_launcher_version_fkey') THEN
+		ALTER TABLE "launcher_artifact"
+			ADD CONSTRAINT "launcher_artifact_launcher_version_fkey" FOREIGN KEY ("launcher_version")
+				REFERENCES "launcher" ("version") ON UPDATE CASCADE ON DELETE CASCADE;
+	END IF;
+END
+$$;
+
+DROP TRIGGER IF EXISTS "launcher_artifact_notify_cache" ON "launcher_artifact";
+CREATE TRIGGER "launcher_artifact_notify_cache"
+	AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON "launcher_artifact"
+	FOR EACH STATEMENT EXECUTE FUNCTION "loginapi_notify_cache"('launcher');
+
+-- security relevant account events
+CREATE TABLE IF NOT EXISTS "audit_log" (
+	"id" BIGSERIAL PRIMARY KEY,
+	"account_id" INTEGER REFERENCES "account" ("id") ON DELETE SET NULL,
+	"event" VARCHAR(64) NOT NULL,
+	"ip" INET,
+	"details" JSONB NOT NULL DEFAULT '{}',
+	"created_at" TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS "audit_log_account_id_idx" ON "audit_log" ("account_id", "created_at");
@@ -0,0 +1,3 @@
+-- base64 encoded Ed25519 public key of the key embedded in the launcher build
+ALTER TABLE "launcher_artifact"
+	ADD COLUMN IF NOT EXISTS "public_key" TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,27 @@
+-- game modes the launcher can log in to
+CREATE TABLE IF NOT EXISTS "mode" (
+	"id" INTEGER PRIMARY KEY,
+	"name" VARCHAR(64) NOT NULL,
+	"description" TEXT NOT NULL DEFAULT '',
+	"enabled" BOOLEAN NOT NULL DEFAULT TRUE,
+	-- world select server of the mode
+	"world_host" TEXT NOT NULL DEFAULT '',
+	"world_port" INTEGER NOT NULL DEFAULT 51000,
+	-- only accounts with this role can use the mode
+	"required_role" VARCHAR(32),
+	"maintenance" BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+INSERT INTO "mode" ("id", "name", "description") VALUES
+	(0, 'Live', 'Live server')
+ON CONFLICT DO NOTHING;
+
+-- modes that already have files keep working, their names can be changed later
+INSERT INTO "mode" ("id", "name")
+SELECT DISTINCT "mode", 'Mode ' || "mode" FROM "filehash"
+ON CONFLICT DO NOTHING;
+
+DROP TRIGGER IF EXISTS "mode_notify_cache" ON "mode";
+CREATE TRIGGER "mode_notify_cache"
+	AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON "mode"
+	FOR EACH STATEMENT EXECUTE FUNCTION "loginapi_notify_cache"('mode');
@@ -0,0 +1,3 @@
+-- additional arguments the client is started with for the mode
+ALTER TABLE "mode"
+	ADD COLUMN IF NOT EXISTS "launch_args" TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,36 @@
+CREATE TABLE IF NOT EXISTS "role" (
+	"name" VARCHAR(32) PRIMARY KEY,
+	"description" TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS "role_permission" (
+	"role" VARCHAR(32) NOT NULL REFERENCES "role" ("name") ON DELETE CASCADE,
+	-- "*" grants all permissions, "admin.*" all admin permissions
+	"permission" VARCHAR(64) NOT NULL,
+	PRIMARY KEY ("role", "permission")
+);
+
+-- accounts without roles are players
+CREATE TABLE IF NOT EXISTS "account_role" (
+	"account_id" INTEGER NOT NULL REFERENCES "account" ("id") ON DELETE CASCADE,
+	"role" VARCHAR(32) NOT NULL REFERENCES "role" ("name") ON DELETE CASCADE,
+	PRIMARY KEY ("account_id", "role")
+);
+
+INSERT INTO "role" ("name", "description") VALUES
+	('player', 'Player'),
+	('tester', 'Tester with access to test modes'),
+	('gm', 'Game master'),
+	('admin', 'Administrator')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO "role_permission" ("role", "permission") VALUES
+	('tester', 'mode.test'),
+	('gm', 'mode.test'),
+	('gm', 'maintenance.bypass'),
+	('admin', '*')
+ON CONFLICT DO NOTHING;
+
+-- only accounts with this permission can use the mode
+ALTER TABLE "mode"
+	ADD COLUMN IF NOT EXISTS "required_permission" VARCHAR(64);
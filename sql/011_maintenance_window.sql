@@ -0,0 +1,17 @@
+-- scheduled maintenance, global if mode is NULL
+CREATE TABLE IF NOT EXISTS "maintenance_window" (
+	"id" SERIAL PRIMARY KEY,
+	"mode" INTEGER REFERENCES "mode" ("id") ON DELETE CASCADE,
+	"starts_at" TIMESTAMPTZ NOT NULL,
+	"ends_at" TIMESTAMPTZ NOT NULL,
+	"message" TEXT NOT NULL DEFAULT '',
+	"created_at" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	CHECK ("ends_at" > "starts_at")
+);
+
+CREATE INDEX IF NOT EXISTS "maintenance_window_ends_at_idx" ON "maintenance_window" ("ends_at");
+
+DROP TRIGGER IF EXISTS "maintenance_window_notify_cache" ON "maintenance_window";
+CREATE TRIGGER "maintenance_window_notify_cache"
+	AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON "maintenance_window"
+	FOR EACH STATEMENT EXECUTE FUNCTION "loginapi_notify_cache"('maintenance');
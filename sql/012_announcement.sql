@@ -0,0 +1,24 @@
+-- news and announcements shown in the launcher
+CREATE TABLE IF NOT EXISTS "announcement" (
+	"id" SERIAL PRIMARY KEY,
+	"title" TEXT NOT NULL,
+	-- markdown
+	"body" TEXT NOT NULL DEFAULT '',
+	-- higher priorities are shown first
+	"priority" INTEGER NOT NULL DEFAULT 0,
+	-- empty targets match everyone
+	"channels" TEXT[] NOT NULL DEFAULT '{}',
+	"modes" INTEGER[] NOT NULL DEFAULT '{}',
+	"roles" TEXT[] NOT NULL DEFAULT '{}',
+	"publish_at" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	"expire_at" TIMESTAMPTZ,
+	-- ID of imported feed items
+	"guid" TEXT UNIQUE,
+	"created_at" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	"updated_at" TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+DROP TRIGGER IF EXISTS "announcement_notify_cache" ON "announcement";
+CREATE TRIGGER "announcement_notify_cache"
+	AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON "announcement"
+	FOR EACH STATEMENT EXECUTE FUNCTION "loginapi_notify_cache"('announcement');
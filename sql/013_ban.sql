@@ -0,0 +1,24 @@
+-- account and IP bans
+CREATE TABLE IF NOT EXISTS "ban" (
+	"id" SERIAL PRIMARY KEY,
+	"account_id" INTEGER REFERENCES "account" ("id") ON DELETE CASCADE,
+	"cidr" CIDR,
+	"reason" TEXT NOT NULL DEFAULT '',
+	-- NULL for bans issued with the admin API key
+	"issued_by" INTEGER REFERENCES "account" ("id") ON DELETE SET NULL,
+	"starts_at" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	-- NULL for permanent bans
+	"expires_at" TIMESTAMPTZ,
+	"lifted_at" TIMESTAMPTZ,
+	"lifted_by" INTEGER REFERENCES "account" ("id") ON DELETE SET NULL,
+	CHECK ("account_id" IS NOT NULL OR "cidr" IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS "ban_account_id_idx" ON "ban" ("account_id");
+CREATE INDEX IF NOT EXISTS "ban_cidr_idx" ON "ban" USING GIST ("cidr" inet_ops);
+
+-- game masters can manage bans
+INSERT INTO "role_permission" ("role", "permission") VALUES
+	('gm', 'admin'),
+	('gm', 'admin.bans')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,8 @@
+-- incremented to revoke all login tokens and the game token of the account
+ALTER TABLE "account"
+	ADD COLUMN IF NOT EXISTS "session_generation" INTEGER NOT NULL DEFAULT 0;
+
+DROP TRIGGER IF EXISTS "account_session_notify_cache" ON "account";
+CREATE TRIGGER "account_session_notify_cache"
+	AFTER UPDATE OF "session_generation" ON "account"
+	FOR EACH STATEMENT EXECUTE FUNCTION "loginapi_notify_cache"('session');
@@ -0,0 +1,34 @@
+-- usernames are unique regardless of case, accounts whose usernames only differ in case have to be renamed first,
+-- the migration stops with the list of them instead of failing to build the index
+DO $$
+DECLARE
+	collisions TEXT;
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM pg_indexes WHERE indexname = 'account_username_lower_idx') THEN
+
+		SELECT string_agg(format('%s (IDs %s)', "usernames", "ids"), ', ')
+		INTO collisions
+		FROM (
+			SELECT string_agg("username", ' / ' ORDER BY "id") AS "usernames", string_agg("id"::TEXT, ', ' ORDER BY "id") AS "ids"
+			FROM "account"
+			GROUP BY LOWER("username")
+			HAVING COUNT(*) > 1
+		) AS "collision";
+
+		IF collisions IS NOT NULL THEN
+			RAISE EXCEPTION 'usernames only differing in case: %', collisions
+				USING HINT = 'rename all but one account of each username, see "Registration" in the README';
+		END IF;
+	END IF;
+END
+$$;
+
+CREATE UNIQUE INDEX IF NOT EXISTS "account_username_lower_idx" ON "account" (LOWER("username"));
+
+-- codes required to register if REGISTRATION is set to invite
+CREATE TABLE IF NOT EXISTS "invite_code" (
+	"code" VARCHAR(32) PRIMARY KEY,
+	"max_uses" INTEGER NOT NULL DEFAULT 1,
+	"uses" INTEGER NOT NULL DEFAULT 0,
+	"created_at" TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,27 @@
+-- invite codes for registration or access to a mode, created in batches
+ALTER TABLE "invite_code"
+	ADD COLUMN IF NOT EXISTS "batch" VARCHAR(64) NOT NULL DEFAULT '',
+	ADD COLUMN IF NOT EXISTS "target" VARCHAR(16) NOT NULL DEFAULT 'registration' CHECK ("target" IN ('registration', 'mode')),
+	-- mode the code grants access to, only for mode codes
+	ADD COLUMN IF NOT EXISTS "mode" INTEGER REFERENCES "mode" ("id") ON DELETE CASCADE,
+	ADD COLUMN IF NOT EXISTS "expires_at" TIMESTAMPTZ,
+	ADD COLUMN IF NOT EXISTS "revoked_at" TIMESTAMPTZ,
+	-- NULL for codes created with the admin API key
+	ADD COLUMN IF NOT EXISTS "created_by" INTEGER REFERENCES "account" ("id") ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS "invite_code_batch_idx" ON "invite_code" ("batch");
+
+CREATE TABLE IF NOT EXISTS "invite_redemption" (
+	"code" VARCHAR(32) NOT NULL REFERENCES "invite_code" ("code") ON DELETE CASCADE,
+	"account_id" INTEGER NOT NULL REFERENCES "account" ("id") ON DELETE CASCADE,
+	"redeemed_at" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	PRIMARY KEY ("code", "account_id")
+);
+
+-- accounts that may use a mode regardless of its required role and permission
+CREATE TABLE IF NOT EXISTS "account_mode_grant" (
+	"account_id" INTEGER NOT NULL REFERENCES "account" ("id") ON DELETE CASCADE,
+	"mode" INTEGER NOT NULL REFERENCES "mode" ("id") ON DELETE CASCADE,
+	"granted_at" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	PRIMARY KEY ("account_id", "mode")
+);
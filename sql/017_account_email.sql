@@ -0,0 +1,23 @@
+-- optional email of the account, only verified addresses receive password resets
+ALTER TABLE "account"
+	ADD COLUMN IF NOT EXISTS "email" VARCHAR(254),
+	ADD COLUMN IF NOT EXISTS "email_verified_at" TIMESTAMPTZ;
+
+CREATE UNIQUE INDEX IF NOT EXISTS "account_email_lower_idx" ON "account" (LOWER("email")) WHERE "email" IS NOT NULL;
+
+-- one-time codes sent to the account
+CREATE TABLE IF NOT EXISTS "account_code" (
+	"id" SERIAL PRIMARY KEY,
+	"account_id" INTEGER NOT NULL REFERENCES "account" ("id") ON DELETE CASCADE,
+	-- email_verification or password_reset
+	"purpose" VARCHAR(32) NOT NULL,
+	-- SHA-256 of the code
+	"code_hash" BYTEA NOT NULL,
+	-- address a verification code was sent to
+	"email" VARCHAR(254),
+	"created_at" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	"expires_at" TIMESTAMPTZ NOT NULL,
+	"used_at" TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS "account_code_account_id_idx" ON "account_code" ("account_id", "purpose");
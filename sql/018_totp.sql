@@ -0,0 +1,21 @@
+-- TOTP secret of the account, pending until the first code is confirmed
+CREATE TABLE IF NOT EXISTS "account_totp" (
+	"account_id" INTEGER PRIMARY KEY REFERENCES "account" ("id") ON DELETE CASCADE,
+	-- base32 encoded
+	"secret" VARCHAR(64) NOT NULL,
+	"created_at" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	"enabled_at" TIMESTAMPTZ,
+	-- codes of this time step and earlier are rejected
+	"last_used_step" BIGINT NOT NULL DEFAULT 0
+);
+
+-- one-time codes to log in without the authenticator
+CREATE TABLE IF NOT EXISTS "account_recovery_code" (
+	"id" SERIAL PRIMARY KEY,
+	"account_id" INTEGER NOT NULL REFERENCES "account" ("id") ON DELETE CASCADE,
+	-- SHA-256 of the code
+	"code_hash" BYTEA NOT NULL,
+	"used_at" TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS "account_recovery_code_account_id_idx" ON "account_recovery_code" ("account_id");
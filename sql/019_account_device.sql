@@ -0,0 +1,19 @@
+-- devices accounts logged in from, identified by the device ID the launcher sends
+CREATE TABLE IF NOT EXISTS "account_device" (
+	"id" SERIAL PRIMARY KEY,
+	"account_id" INTEGER NOT NULL REFERENCES "account" ("id") ON DELETE CASCADE,
+	-- SHA-256 of the device ID
+	"device_hash" BYTEA NOT NULL,
+	"first_seen" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	"last_seen" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	-- address, launcher version and platform of the last login
+	"ip" INET,
+	"launcher" TEXT NOT NULL DEFAULT '',
+	"platform" TEXT NOT NULL DEFAULT '',
+	-- set once a login from the device was confirmed with the second factor or an emailed code
+	"trusted_at" TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS "account_device_account_id_device_hash_idx" ON "account_device" ("account_id", "device_hash");
+
+-- device confirmation codes are stored in "account_code" with the purpose device
@@ -0,0 +1,119 @@
+// Package totp creates and validates time-based one-time passwords (RFC 6238)
+// with the defaults authenticator apps use: HMAC-SHA1, 6 digits and 30 second steps.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	Digits = 6
+	// seconds
+	Period = 30
+	// bytes, the size of an HMAC-SHA1 key
+	SecretSize = 20
+)
+
+var secretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret returns a random base32 encoded secret
+func GenerateSecret() (string, error) {
+
+	var (
+		secret = make([]byte, SecretSize)
+	)
+
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+
+	return secretEncoding.EncodeToString(secret), nil
+}
+
+// Step returns the time step of the time
+func Step(t time.Time) int64 {
+	return t.Unix() / Period
+}
+
+// Code returns the code of the base32 encoded secret for the time step
+func Code(secret string, step int64) (string, error) {
+
+	var (
+		counter [8]byte
+	)
+
+	key, err := secretEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", err
+	}
+
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	// dynamic truncation of RFC 4226
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", Digits, value%1000000), nil
+}
+
+// Validate checks the code against the steps around the time, skew steps before and after are accepted.
+// It returns the step the code matched, callers should reject codes of steps that were used before.
+func Validate(secret string, code string, t time.Time, skew int64) (step int64, ok bool, err error) {
+
+	var (
+		current = Step(t)
+	)
+
+	if len(code) != Digits {
+		return 0, false, nil
+	}
+
+	for step = current - skew; step <= current+skew; step++ {
+
+		expected, err := Code(secret, step)
+		if err != nil {
+			return 0, false, err
+		}
+
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true, nil
+		}
+	}
+
+	return 0, false, nil
+}
+
+// URI returns the otpauth URI authenticator apps import the secret from, usually as QR code
+func URI(issuer string, account string, secret string) string {
+
+	var (
+		query = url.Values{}
+	)
+
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(Digits))
+	query.Set("period", fmt.Sprint(Period))
+
+	return (&url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: query.Encode(),
+	}).String()
+}
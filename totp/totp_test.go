@@ -0,0 +1,107 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// the SHA1 secret of the RFC 6238 test vectors, "12345678901234567890"
+var rfcSecret = secretEncoding.EncodeToString([]byte("12345678901234567890"))
+
+// RFC 6238 appendix B, SHA1, the last 6 of the 8 digits
+var rfcVectors = []struct {
+	time int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestCodeRFC6238(t *testing.T) {
+
+	for _, vector := range rfcVectors {
+
+		code, err := Code(rfcSecret, Step(time.Unix(vector.time, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if code != vector.code {
+			t.Errorf("code at %d is %s, expected %s", vector.time, code, vector.code)
+		}
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+
+	var (
+		now     = time.Unix(1111111111, 0)
+		current = Step(now)
+	)
+
+	for offset := int64(-2); offset <= 2; offset++ {
+
+		code, err := Code(rfcSecret, current+offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		step, ok, err := Validate(rfcSecret, code, now, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		inWindow := offset >= -1 && offset <= 1
+
+		if ok != inWindow {
+			t.Errorf("code of step offset %d valid: %v, expected %v", offset, ok, inWindow)
+		}
+
+		if ok && step != current+offset {
+			t.Errorf("code of step offset %d matched step %d, expected %d", offset, step, current+offset)
+		}
+	}
+}
+
+func TestValidateRejects(t *testing.T) {
+
+	var (
+		now = time.Unix(59, 0)
+	)
+
+	for _, code := range []string{"", "28708", "2870820", "287083"} {
+
+		_, ok, err := Validate(rfcSecret, code, now, 1)
+		if err != nil || ok {
+			t.Errorf("code %q valid: %v, %v", code, ok, err)
+		}
+	}
+
+	_, _, err := Validate("not base32!", "287082", now, 1)
+	if err == nil {
+		t.Error("invalid secret accepted")
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := secretEncoding.DecodeString(secret)
+	if err != nil || len(key) != SecretSize {
+		t.Fatalf("secret %s decodes to %d bytes, %v", secret, len(key), err)
+	}
+
+	uri := URI("PSForever", "player", secret)
+	if !strings.HasPrefix(uri, "otpauth://totp/PSForever:player?") || !strings.Contains(uri, "secret="+secret) {
+		t.Errorf("unexpected URI %s", uri)
+	}
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"log"
+	"os"
+)
+
+// New device verification modes
+const (
+	// devices are only recorded
+	DeviceVerificationDisabled = "disabled"
+	// accounts with a verified email and without two-factor authentication confirm untrusted devices with a code sent to it
+	DeviceVerificationEmail = "email"
+)
+
+var deviceVerificationMode string
+
+// GetDeviceVerificationMode returns which logins from untrusted devices have to be confirmed
+func GetDeviceVerificationMode() string {
+
+	if deviceVerificationMode == "" {
+
+		deviceVerificationMode = os.Getenv("NEW_DEVICE_VERIFICATION")
+
+		switch deviceVerificationMode {
+		case "":
+			deviceVerificationMode = DeviceVerificationDisabled
+		case DeviceVerificationDisabled:
+		case DeviceVerificationEmail:
+			if _, mailEnabled := GetMailer(); !mailEnabled {
+				log.Fatalf("NEW_DEVICE_VERIFICATION %s requires MAIL", deviceVerificationMode)
+			}
+		default:
+			log.Fatalf("Invalid NEW_DEVICE_VERIFICATION: %s", deviceVerificationMode)
+		}
+	}
+
+	return deviceVerificationMode
+}
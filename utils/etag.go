@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+)
+
+// CreateETag returns a strong ETag over all parts
+func CreateETag(parts ...string) string {
+
+	var (
+		hasher = sha256.New()
+	)
+
+	for _, part := range parts {
+		hasher.Write([]byte(part))
+		// separate parts so moving bytes between them changes the tag
+		hasher.Write([]byte{0})
+	}
+
+	return fmt.Sprintf(`"%x"`, hasher.Sum(nil))
+}
+
+// MatchesETag returns true if the If-None-Match header value matches the ETag
+func MatchesETag(ifNoneMatch string, etag string) bool {
+
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+
+		candidate = strings.TrimSpace(candidate)
+
+		// If-None-Match uses the weak comparison
+		candidate = strings.TrimPrefix(candidate, "W/")
+
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"log"
+	"os"
+	"strconv"
+
+	"PSF-LoginAPI/mail"
+)
+
+// Mail modes
+const (
+	MailDisabled = "disabled"
+	MailSMTP     = "smtp"
+	// messages are written to the outbox directory
+	MailFile = "file"
+)
+
+const defaultPasswordResetRateLimit = "5/1h"
+
+var mailer mail.Mailer
+var mailerLoaded bool
+var passwordResetRateLimiter *RateLimiter
+
+// GetMailer returns the mailer emails are sent with, false if sending emails is disabled
+func GetMailer() (mail.Mailer, bool) {
+
+	if !mailerLoaded {
+
+		var (
+			from = os.Getenv("MAIL_FROM")
+		)
+
+		mailerLoaded = true
+
+		switch mode := os.Getenv("MAIL"); mode {
+		case "", MailDisabled:
+			return nil, false
+
+		case MailSMTP:
+			port, err := strconv.Atoi(os.Getenv("SMTP_PORT"))
+			if err != nil {
+				log.Fatalf("Invalid SMTP_PORT: %s", os.Getenv("SMTP_PORT"))
+			}
+
+			mailer = &mail.SMTPMailer{
+				Host:     os.Getenv("SMTP_HOST"),
+				Port:     port,
+				Username: os.Getenv("SMTP_USER"),
+				Password: os.Getenv("SMTP_PASS"),
+				From:     from,
+			}
+
+		case MailFile:
+			directory := os.Getenv("MAIL_OUTBOX")
+			if directory == "" {
+				directory = "outbox"
+			}
+
+			mailer = &mail.FileMailer{
+				Directory: directory,
+				From:      from,
+			}
+
+		default:
+			log.Fatalf("Invalid MAIL: %s", mode)
+		}
+
+		if from == "" {
+			log.Fatalf("MAIL_FROM is required to send emails")
+		}
+	}
+
+	return mailer, mailer != nil
+}
+
+// GetPasswordResetRateLimiter returns the limiter for password reset requests per IP
+func GetPasswordResetRateLimiter() *RateLimiter {
+
+	if passwordResetRateLimiter == nil {
+		passwordResetRateLimiter = getRateLimiter("PASSWORD_RESET_RATE_LIMIT", defaultPasswordResetRateLimit)
+	}
+
+	return passwordResetRateLimiter
+}
@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"log"
+	"os"
+	"runtime"
+	"strconv"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"PSF-LoginAPI/password"
+)
+
+var passwordHasher password.Hasher
+var passwordPool *password.Pool
+var dummyPasswordHash string
+var passwordPolicy *password.Policy
+
+// GetPasswordHasher returns the hasher new password hashes are created with,
+// hashes of other algorithms or parameters are replaced on login
+func GetPasswordHasher() password.Hasher {
+
+	if passwordHasher == nil {
+
+		switch algorithm := os.Getenv("PASSWORD_HASH"); algorithm {
+		case "", "bcrypt":
+			passwordHasher = &password.Bcrypt{
+				Cost: getPasswordParameter("PASSWORD_BCRYPT_COST", bcrypt.DefaultCost, bcrypt.MinCost, bcrypt.MaxCost),
+			}
+
+		case "argon2id":
+			passwordHasher = &password.Argon2id{
+				Memory:  uint32(getPasswordParameter("PASSWORD_ARGON2_MEMORY", 64*1024, 8, 4*1024*1024)),
+				Time:    uint32(getPasswordParameter("PASSWORD_ARGON2_TIME", 3, 1, 100)),
+				Threads: uint8(getPasswordParameter("PASSWORD_ARGON2_THREADS", 4, 1, 255)),
+			}
+
+		default:
+			log.Fatalf("Invalid PASSWORD_HASH: %s", algorithm)
+		}
+	}
+
+	return passwordHasher
+}
+
+func getPasswordParameter(name string, defaultValue int, minValue int, maxValue int) int {
+
+	var (
+		value = os.Getenv(name)
+	)
+
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < minValue || parsed > maxValue {
+		log.Fatalf("Invalid %s: %s, has to be between %d and %d", name, value, minValue, maxValue)
+	}
+
+	return parsed
+}
+
+// GetPasswordPolicy returns the rules new passwords have to follow
+func GetPasswordPolicy() *password.Policy {
+
+	if passwordPolicy == nil {
+		passwordPolicy = &password.Policy{
+			MinLength:  getPasswordParameter("PASSWORD_MIN_LENGTH", 8, 1, 72),
+			MaxLength:  getPasswordParameter("PASSWORD_MAX_LENGTH", 72, 1, 72),
+			MinClasses: getPasswordParameter("PASSWORD_MIN_CLASSES", 1, 1, 4),
+		}
+	}
+
+	return passwordPolicy
+}
+
+// GetDummyPasswordHash returns a hash of a random password created with the configured hasher,
+// passwords of unknown accounts are checked against it so they take as long as the ones of known accounts
+func GetDummyPasswordHash() string {
+
+	if dummyPasswordHash == "" {
+
+		var (
+			err error
+		)
+
+		dummyPasswordHash, err = GetPasswordHasher().Hash(RandString(32))
+		if err != nil {
+			log.Fatalf("Failed to create dummy password hash: %s", err.Error())
+		}
+	}
+
+	return dummyPasswordHash
+}
+
+// GetPasswordPool returns the pool passwords are hashed and verified on
+func GetPasswordPool() *password.Pool {
+
+	if passwordPool == nil {
+		passwordPool = password.NewPool(
+			getPasswordParameter("PASSWORD_WORKERS", runtime.NumCPU(), 1, 1024),
+			getPasswordParameter("PASSWORD_QUEUE", 64, 0, 65536),
+		)
+	}
+
+	return passwordPool
+}
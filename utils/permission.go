@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Roles
+const (
+	// role of accounts without roles
+	RolePlayer = "player"
+	RoleTester = "tester"
+	RoleGM     = "gm"
+	RoleAdmin  = "admin"
+)
+
+// Permissions
+const (
+	PermissionAll = "*"
+
+	PermissionModeTest          = "mode.test"
+	PermissionMaintenanceBypass = "maintenance.bypass"
+
+	// required for any admin API access
+	PermissionAdmin              = "admin"
+	PermissionAdminChannels      = "admin.channels"
+	PermissionAdminRoles         = "admin.roles"
+	PermissionAdminMaintenance   = "admin.maintenance"
+	PermissionAdminAnnouncements = "admin.announcements"
+	PermissionAdminBans          = "admin.bans"
+	PermissionAdminInvites       = "admin.invites"
+)
+
+// ModeGrantPermission returns the permission accounts get for a mode from an invite code,
+// it grants access to the mode regardless of its required role and permission
+func ModeGrantPermission(mode int64) string {
+	return "mode.grant." + strconv.FormatInt(mode, 10)
+}
+
+// HasPermission returns true if the permissions grant the permission.
+// "*" grants all permissions, "admin.*" grants "admin" and all permissions starting with "admin.".
+func HasPermission(permissions []string, permission string) bool {
+
+	for _, granted := range permissions {
+
+		if granted == PermissionAll || granted == permission {
+			return true
+		}
+
+		if strings.HasSuffix(granted, ".*") {
+
+			prefix := strings.TrimSuffix(granted, ".*")
+
+			if permission == prefix || strings.HasPrefix(permission, prefix+".") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// ClaimStrings returns the string list claim, empty if the claim is missing or not a string list
+func ClaimStrings(claims jwt.MapClaims, key string) (values []string) {
+
+	var (
+		list, _ = claims[key].([]interface{})
+	)
+
+	for _, item := range list {
+		if value, ok := item.(string); ok {
+			values = append(values, value)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestHasPermission(t *testing.T) {
+
+	tests := []struct {
+		permissions []string
+		permission  string
+		expected    bool
+	}{
+		{nil, PermissionAdmin, false},
+		{[]string{}, PermissionModeTest, false},
+		{[]string{PermissionAll}, PermissionAdminBans, true},
+		{[]string{PermissionAll}, ModeGrantPermission(2), true},
+		{[]string{PermissionAdminBans}, PermissionAdminBans, true},
+		{[]string{PermissionAdminBans}, PermissionAdmin, false},
+		{[]string{PermissionAdminBans}, PermissionAdminRoles, false},
+		{[]string{"admin.*"}, PermissionAdmin, true},
+		{[]string{"admin.*"}, PermissionAdminRoles, true},
+		{[]string{"admin.*"}, PermissionModeTest, false},
+		// the wildcard only matches whole segments
+		{[]string{"admin.*"}, "administrator", false},
+		{[]string{"mode.*"}, ModeGrantPermission(2), true},
+		{[]string{"mode.grant.*"}, PermissionModeTest, false},
+		// only a trailing wildcard is special
+		{[]string{"admin*"}, PermissionAdminRoles, false},
+		{[]string{"*.bans"}, PermissionAdminBans, false},
+		{[]string{PermissionModeTest, "admin.*"}, PermissionAdminInvites, true},
+	}
+
+	for _, test := range tests {
+
+		if result := HasPermission(test.permissions, test.permission); result != test.expected {
+			t.Errorf("HasPermission(%v, %q) = %v, expected %v", test.permissions, test.permission, result, test.expected)
+		}
+	}
+}
+
+func TestClaimStrings(t *testing.T) {
+
+	tests := []struct {
+		claims   jwt.MapClaims
+		expected []string
+	}{
+		{jwt.MapClaims{}, nil},
+		{jwt.MapClaims{"permissions": "admin"}, nil},
+		{jwt.MapClaims{"permissions": []interface{}{"admin", 1, "mode.test"}}, []string{"admin", "mode.test"}},
+	}
+
+	for _, test := range tests {
+
+		result := ClaimStrings(test.claims, "permissions")
+
+		if len(result) != len(test.expected) {
+			t.Errorf("ClaimStrings(%v) = %v, expected %v", test.claims, result, test.expected)
+			continue
+		}
+
+		for i := range result {
+			if result[i] != test.expected[i] {
+				t.Errorf("ClaimStrings(%v) = %v, expected %v", test.claims, result, test.expected)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"log"
+	"net"
+	"os"
+	"strings"
+)
+
+var trustedProxies []string
+var trustedProxiesLoaded bool
+
+// GetTrustedProxies returns the addresses and CIDR ranges of the reverse proxies
+// whose forwarded client IP is used, none if the API is reached directly
+func GetTrustedProxies() []string {
+
+	if !trustedProxiesLoaded {
+
+		trustedProxiesLoaded = true
+
+		for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+
+			proxy = strings.TrimSpace(proxy)
+			if proxy == "" {
+				continue
+			}
+
+			_, _, err := net.ParseCIDR(proxy)
+			if err != nil && net.ParseIP(proxy) == nil {
+				log.Fatalf("Invalid TRUSTED_PROXIES entry: %s", proxy)
+			}
+
+			trustedProxies = append(trustedProxies, proxy)
+		}
+	}
+
+	return trustedProxies
+}
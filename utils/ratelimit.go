@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimiter allows a number of requests per key in fixed time windows
+type RateLimiter struct {
+	mutex sync.Mutex
+
+	limit  int
+	window time.Duration
+
+	windows   map[string]*rateWindow
+	lastSweep time.Time
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:     limit,
+		window:    window,
+		windows:   make(map[string]*rateWindow),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow counts a request of the key, returns false if the key used up its requests of the current window
+func (limiter *RateLimiter) Allow(key string) bool {
+
+	var (
+		now = time.Now()
+	)
+
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	// drop windows that ended so the map does not grow with every key ever seen
+	if now.Sub(limiter.lastSweep) > limiter.window {
+
+		for windowKey, window := range limiter.windows {
+			if now.Sub(window.start) > limiter.window {
+				delete(limiter.windows, windowKey)
+			}
+		}
+
+		limiter.lastSweep = now
+	}
+
+	window, exists := limiter.windows[key]
+	if !exists || now.Sub(window.start) > limiter.window {
+		window = &rateWindow{
+			start: now,
+		}
+		limiter.windows[key] = window
+	}
+
+	if window.count >= limiter.limit {
+		return false
+	}
+
+	window.count++
+
+	return true
+}
+
+// returns the limiter of the environment variable configured as <count>/<duration>
+func getRateLimiter(name string, defaultRateLimit string) *RateLimiter {
+
+	var (
+		err error
+
+		limit  int
+		window time.Duration
+
+		rateLimit = os.Getenv(name)
+	)
+
+	if rateLimit == "" {
+		rateLimit = defaultRateLimit
+	}
+
+	count, duration, found := strings.Cut(rateLimit, "/")
+	if found {
+		limit, err = strconv.Atoi(count)
+	}
+	if found && err == nil {
+		window, err = time.ParseDuration(duration)
+	}
+	if !found || err != nil || limit < 1 || window <= 0 {
+		log.Fatalf("Invalid %s: %s, has to be <count>/<duration>", name, rateLimit)
+	}
+
+	return NewRateLimiter(limit, window)
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"bufio"
+	"log"
+	"os"
+	"strings"
+)
+
+// Registration modes
+const (
+	RegistrationDisabled = "disabled"
+	RegistrationOpen     = "open"
+	// registration requires an invite code
+	RegistrationInvite = "invite"
+)
+
+const defaultRegistrationRateLimit = "5/1h"
+
+var registrationMode string
+var registrationRateLimiter *RateLimiter
+var blockedUsernames []string
+var blockedUsernamesLoaded bool
+
+// GetRegistrationMode returns if accounts can be registered through the API and if they need an invite code
+func GetRegistrationMode() string {
+
+	if registrationMode == "" {
+
+		registrationMode = os.Getenv("REGISTRATION")
+
+		switch registrationMode {
+		case "":
+			registrationMode = RegistrationDisabled
+		case RegistrationDisabled, RegistrationOpen, RegistrationInvite:
+		default:
+			log.Fatalf("Invalid REGISTRATION: %s", registrationMode)
+		}
+	}
+
+	return registrationMode
+}
+
+// GetRegistrationRateLimiter returns the limiter for registrations per IP
+func GetRegistrationRateLimiter() *RateLimiter {
+
+	if registrationRateLimiter == nil {
+		registrationRateLimiter = getRateLimiter("REGISTRATION_RATE_LIMIT", defaultRegistrationRateLimit)
+	}
+
+	return registrationRateLimiter
+}
+
+// GetBlockedUsernames returns the lowercase words usernames must not contain,
+// read from the file of REGISTRATION_BLOCKED_NAMES with one word per line
+func GetBlockedUsernames() []string {
+
+	if !blockedUsernamesLoaded {
+
+		var (
+			path = os.Getenv("REGISTRATION_BLOCKED_NAMES")
+		)
+
+		blockedUsernamesLoaded = true
+
+		if path == "" {
+			return nil
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			log.Fatalf("Could not open REGISTRATION_BLOCKED_NAMES: %s", err.Error())
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+
+			word := strings.ToLower(strings.TrimSpace(scanner.Text()))
+
+			// skip empty lines and comments
+			if word != "" && !strings.HasPrefix(word, "#") {
+				blockedUsernames = append(blockedUsernames, word)
+			}
+		}
+
+		if err = scanner.Err(); err != nil {
+			log.Fatalf("Could not read REGISTRATION_BLOCKED_NAMES: %s", err.Error())
+		}
+	}
+
+	return blockedUsernames
+}
@@ -2,12 +2,14 @@ package utils
 
 import (
 	"context"
+	"crypto/ed25519"
+	cryptorand "crypto/rand"
+	"encoding/base64"
 	"fmt"
 	"log"
 	"math/rand"
 	"net/http"
 	"os"
-	"reflect"
 	"regexp"
 	"time"
 
@@ -17,8 +19,11 @@ import (
 
 const ConstantTime = 1 * time.Second
 
+const defaultCacheTTL = 5 * time.Minute
+
 var versionRegex *regexp.Regexp
 var jwtSigningKey []byte
+var adminAPIKey []byte
 
 var pgConnectionURI string
 
@@ -75,6 +80,29 @@ func GetPostgrePool() *pgxpool.Pool {
 	return pgxPool
 }
 
+// GetCacheTTL returns how long cached database data is used before it is loaded again
+func GetCacheTTL() time.Duration {
+
+	var (
+		err error
+
+		ttl time.Duration
+
+		cacheTTL = os.Getenv("CACHE_TTL")
+	)
+
+	if cacheTTL == "" {
+		return defaultCacheTTL
+	}
+
+	ttl, err = time.ParseDuration(cacheTTL)
+	if err != nil {
+		log.Fatalf("Failed to parse CACHE_TTL: %s", err.Error())
+	}
+
+	return ttl
+}
+
 func getJwtSigningKey() []byte {
 
 	if len(jwtSigningKey) == 0 {
@@ -85,6 +113,95 @@ func getJwtSigningKey() []byte {
 
 }
 
+// Launcher attestation modes
+const (
+	LauncherAttestationDisabled = "disabled"
+	LauncherAttestationOptional = "optional"
+	LauncherAttestationRequired = "required"
+)
+
+var launcherAttestationMode string
+
+// GetLauncherAttestationMode returns if launchers can or have to prove their build with a signed challenge
+func GetLauncherAttestationMode() string {
+
+	if launcherAttestationMode == "" {
+
+		launcherAttestationMode = os.Getenv("LAUNCHER_ATTESTATION")
+
+		switch launcherAttestationMode {
+		case "":
+			launcherAttestationMode = LauncherAttestationDisabled
+		case LauncherAttestationDisabled, LauncherAttestationOptional, LauncherAttestationRequired:
+		default:
+			log.Fatalf("Invalid LAUNCHER_ATTESTATION: %s", launcherAttestationMode)
+		}
+	}
+
+	return launcherAttestationMode
+}
+
+var responseSigningKey ed25519.PrivateKey
+var responseSigningKeyLoaded bool
+
+// GetResponseSigningKey returns the key responses are signed with, false if response signing is disabled
+func GetResponseSigningKey() (ed25519.PrivateKey, bool) {
+
+	if !responseSigningKeyLoaded {
+
+		var (
+			err error
+
+			key []byte
+
+			encodedKey = os.Getenv("RESPONSE_SIGNING_KEY")
+		)
+
+		responseSigningKeyLoaded = true
+
+		if encodedKey == "" {
+			return nil, false
+		}
+
+		key, err = base64.StdEncoding.DecodeString(encodedKey)
+		if err != nil {
+			log.Fatalf("Failed to decode RESPONSE_SIGNING_KEY: %s", err.Error())
+		}
+
+		// accept the seed as well as the full private key
+		switch len(key) {
+		case ed25519.SeedSize:
+			responseSigningKey = ed25519.NewKeyFromSeed(key)
+		case ed25519.PrivateKeySize:
+			responseSigningKey = key
+		default:
+			log.Fatalf("RESPONSE_SIGNING_KEY has to be a base64 encoded Ed25519 seed or private key")
+		}
+	}
+
+	return responseSigningKey, responseSigningKey != nil
+}
+
+// GetTOTPIssuer returns the name authenticator apps show for the account
+func GetTOTPIssuer() string {
+
+	if issuer := os.Getenv("TOTP_ISSUER"); issuer != "" {
+		return issuer
+	}
+
+	return "PSForever"
+}
+
+// GetAdminAPIKey returns the key required for the admin API, empty if the admin API is disabled
+func GetAdminAPIKey() []byte {
+
+	if len(adminAPIKey) == 0 {
+		adminAPIKey = []byte(os.Getenv("ADMIN_API_KEY"))
+	}
+
+	return adminAPIKey
+}
+
 func getLauncherVersionRegex() *regexp.Regexp {
 
 	if versionRegex == nil {
@@ -99,36 +216,24 @@ func ExtractLauncherVersion(request *http.Request) []string {
 	return getLauncherVersionRegex().FindStringSubmatch(request.UserAgent())
 }
 
-// https://stackoverflow.com/a/71235876
-// Creates wrapper function and sets it to the passed pointer to function
-func ConstantTimeCall[T any](constantTime time.Duration, function T) T {
-
-	// call function
-	v := reflect.MakeFunc(
-		reflect.TypeOf(function), func(in []reflect.Value) (values []reflect.Value) {
-
-			// get the time to enforce constant time
-			var (
-				remainingTime time.Duration
+// CallConstantTime runs the function and returns its result once the duration passed since the call.
+// Waiting ends early with the error of the context if the context is cancelled.
+func CallConstantTime[T any](ctx context.Context, duration time.Duration, function func(context.Context) T) (result T, err error) {
 
-				timestamp = time.Now()
-			)
-
-			// reconstruct and call original function
-			f := reflect.ValueOf(function)
-			values = f.Call(in)
-
-			// calc remaining constant time
-			remainingTime = constantTime - time.Now().Sub(timestamp)
+	var (
+		timer = time.NewTimer(duration)
+	)
+	defer timer.Stop()
 
-			// sleep to achieve constant time
-			time.Sleep(remainingTime)
+	result = function(ctx)
 
-			return values
-		},
-	)
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
 
-	return v.Interface().(T)
+	return
 }
 
 type CustomClaims struct {
@@ -137,7 +242,23 @@ type CustomClaims struct {
 	Mode   int64 `json:"mode"`
 }
 
+// lifetime of login tokens
+const TokenLifetime = 10 * time.Minute
+
+// Token purposes, tokens with a purpose claim are not accepted as login token
+const (
+	TokenPurposeAttestation = "attestation"
+	// login waiting for the second factor
+	TokenPurposeTwoFactor = "2fa"
+	// login from an untrusted device waiting for the emailed code
+	TokenPurposeDevice = "device"
+)
+
 func GenerateToken(additionalClaims *jwt.MapClaims) (string, error) {
+	return GenerateTokenWithLifetime(additionalClaims, TokenLifetime)
+}
+
+func GenerateTokenWithLifetime(additionalClaims *jwt.MapClaims, lifetime time.Duration) (string, error) {
 
 	var (
 		timeNow = time.Now()
@@ -146,7 +267,7 @@ func GenerateToken(additionalClaims *jwt.MapClaims) (string, error) {
 			"iss": "Launcher Auth API",
 			"iat": timeNow.Unix(),
 			"nbf": timeNow.Unix(),
-			"exp": timeNow.Add(10 * time.Minute).Unix(),
+			"exp": timeNow.Add(lifetime).Unix(),
 		}
 	)
 
@@ -195,3 +316,26 @@ func RandString(n int) string {
 	}
 	return string(b)
 }
+
+// without characters that are easily mistaken for each other
+const codeBytes = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// RandomCode returns a code of n characters for players to type, it is safe to use for secrets
+func RandomCode(n int) (string, error) {
+
+	var (
+		b = make([]byte, n)
+	)
+
+	_, err := cryptorand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	// 32 characters, so every byte maps to a character without bias
+	for i := range b {
+		b[i] = codeBytes[int(b[i])%len(codeBytes)]
+	}
+
+	return string(b), nil
+}
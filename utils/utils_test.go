@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCallConstantTimeWaits(t *testing.T) {
+
+	var (
+		duration = 50 * time.Millisecond
+		start    = time.Now()
+	)
+
+	result, err := CallConstantTime(context.Background(), duration, func(context.Context) int { return 42 })
+	if err != nil || result != 42 {
+		t.Fatalf("CallConstantTime returned %d, %v", result, err)
+	}
+
+	if elapsed := time.Since(start); elapsed < duration {
+		t.Fatalf("CallConstantTime returned after %s, expected at least %s", elapsed, duration)
+	}
+}
+
+func TestCallConstantTimeCancelled(t *testing.T) {
+
+	var (
+		ctx, cancel = context.WithCancel(context.Background())
+		start       = time.Now()
+	)
+
+	result, err := CallConstantTime(ctx, time.Hour, func(context.Context) int {
+		cancel()
+		return 42
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("CallConstantTime returned %v after cancel, expected context.Canceled", err)
+	}
+
+	if result != 42 {
+		t.Fatalf("CallConstantTime returned %d, expected the result of the function", result)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("CallConstantTime waited %s after cancel", elapsed)
+	}
+}
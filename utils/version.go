@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// LauncherVersion is a four part launcher version, major.minor.patch.build
+type LauncherVersion [4]int
+
+var minimumLauncherVersion *LauncherVersion
+
+func ParseLauncherVersion(version string) (launcherVersion LauncherVersion, err error) {
+
+	var (
+		parts = strings.Split(version, ".")
+	)
+
+	if len(parts) != len(launcherVersion) {
+		err = fmt.Errorf("launcher version [%s] does not have %d parts", version, len(launcherVersion))
+		return
+	}
+
+	for i, part := range parts {
+
+		launcherVersion[i], err = strconv.Atoi(part)
+		if err != nil || launcherVersion[i] < 0 {
+			err = fmt.Errorf("launcher version [%s] has invalid part [%s]", version, part)
+			return
+		}
+	}
+
+	return
+}
+
+// Compare returns -1 if the version is lower than the other version, 1 if it is higher and 0 if they are equal
+func (launcherVersion LauncherVersion) Compare(other LauncherVersion) int {
+
+	for i := range launcherVersion {
+
+		if launcherVersion[i] < other[i] {
+			return -1
+		}
+
+		if launcherVersion[i] > other[i] {
+			return 1
+		}
+	}
+
+	return 0
+}
+
+func (launcherVersion LauncherVersion) String() string {
+	return fmt.Sprintf(
+		"%d.%d.%d.%d",
+		launcherVersion[0],
+		launcherVersion[1],
+		launcherVersion[2],
+		launcherVersion[3],
+	)
+}
+
+// GetLauncherVersion returns the launcher version from the user agent, false if it is not a PSF Launcher
+func GetLauncherVersion(request *http.Request) (launcherVersion LauncherVersion, ok bool) {
+
+	var (
+		err error
+
+		matches = ExtractLauncherVersion(request)
+	)
+
+	if len(matches) < 2 {
+		return
+	}
+
+	launcherVersion, err = ParseLauncherVersion(matches[1])
+	if err != nil {
+		return
+	}
+
+	return launcherVersion, true
+}
+
+// GetMinimumLauncherVersion returns the lowest launcher version allowed to log in, false if none is configured
+func GetMinimumLauncherVersion() (launcherVersion LauncherVersion, ok bool) {
+
+	var (
+		err error
+	)
+
+	if minimumLauncherVersion == nil {
+
+		var (
+			version = os.Getenv("LAUNCHER_MIN_VERSION")
+		)
+
+		minimumLauncherVersion = &LauncherVersion{}
+
+		if version != "" {
+			*minimumLauncherVersion, err = ParseLauncherVersion(version)
+			if err != nil {
+				log.Fatalf("Failed to parse LAUNCHER_MIN_VERSION: %s", err.Error())
+			}
+		}
+	}
+
+	// the zero version means no minimum is configured
+	return *minimumLauncherVersion, *minimumLauncherVersion != LauncherVersion{}
+}
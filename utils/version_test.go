@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestParseLauncherVersion(t *testing.T) {
+
+	tests := []struct {
+		version  string
+		expected LauncherVersion
+		valid    bool
+	}{
+		{"1.2.3.4", LauncherVersion{1, 2, 3, 4}, true},
+		{"0.0.0.0", LauncherVersion{}, true},
+		{"10.20.300.4000", LauncherVersion{10, 20, 300, 4000}, true},
+		{"1.2.3", LauncherVersion{}, false},
+		{"1.2.3.4.5", LauncherVersion{}, false},
+		{"1.2.x.4", LauncherVersion{}, false},
+		{"1.2.-3.4", LauncherVersion{}, false},
+		{"", LauncherVersion{}, false},
+	}
+
+	for _, test := range tests {
+
+		version, err := ParseLauncherVersion(test.version)
+
+		if (err == nil) != test.valid {
+			t.Errorf("ParseLauncherVersion(%q) returned %v, expected valid %v", test.version, err, test.valid)
+			continue
+		}
+
+		if test.valid && version != test.expected {
+			t.Errorf("ParseLauncherVersion(%q) = %v, expected %v", test.version, version, test.expected)
+		}
+	}
+}
+
+func TestLauncherVersionCompare(t *testing.T) {
+
+	tests := []struct {
+		a, b     LauncherVersion
+		expected int
+	}{
+		{LauncherVersion{1, 2, 3, 4}, LauncherVersion{1, 2, 3, 4}, 0},
+		{LauncherVersion{1, 2, 3, 4}, LauncherVersion{1, 2, 3, 5}, -1},
+		{LauncherVersion{1, 2, 4, 0}, LauncherVersion{1, 2, 3, 9}, 1},
+		{LauncherVersion{2, 0, 0, 0}, LauncherVersion{1, 9, 9, 9}, 1},
+		{LauncherVersion{0, 10, 0, 0}, LauncherVersion{0, 9, 0, 0}, 1},
+	}
+
+	for _, test := range tests {
+
+		if result := test.a.Compare(test.b); result != test.expected {
+			t.Errorf("%s compared to %s = %d, expected %d", test.a, test.b, result, test.expected)
+		}
+
+		if result := test.b.Compare(test.a); result != -test.expected {
+			t.Errorf("%s compared to %s = %d, expected %d", test.b, test.a, result, -test.expected)
+		}
+	}
+}
+
+func TestGetLauncherVersion(t *testing.T) {
+
+	tests := []struct {
+		userAgent string
+		expected  LauncherVersion
+		ok        bool
+	}{
+		{"PSF Launcher v1.2.3.4", LauncherVersion{1, 2, 3, 4}, true},
+		{"PSF Launcher v1.2.3", LauncherVersion{}, false},
+		{"Mozilla/5.0 PSF Launcher v1.2.3.4", LauncherVersion{}, false},
+		{"", LauncherVersion{}, false},
+	}
+
+	for _, test := range tests {
+
+		request, _ := http.NewRequest(http.MethodGet, "/", nil)
+		request.Header.Set("User-Agent", test.userAgent)
+
+		version, ok := GetLauncherVersion(request)
+		if ok != test.ok || version != test.expected {
+			t.Errorf("GetLauncherVersion(%q) = %v, %v, expected %v, %v", test.userAgent, version, ok, test.expected, test.ok)
+		}
+	}
+}